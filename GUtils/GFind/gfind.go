@@ -5,6 +5,11 @@
 // 2025-08-13 	PV 		1.2.0 Support for Windows Recycle Bin
 // 2025-09-07 	PV 		1.3.0 Option -maxdepth
 // 2025-09-08 	PV 		1.3.1 Use MyGlob 1.5 with a queue instead of a stack for a more logical output order
+// 2026-10-19 	PV 		1.4.0 Option -unique
+// 2026-10-19 	PV 		1.5.0 Option -xdev
+// 2026-10-19 	PV 		1.5.1 Option --color for usage
+// 2026-10-19 	PV 		1.5.2 Hidden option --help-format to print help in html, md or man format
+// 2026-10-19 	PV 		1.5.3 Option -unique also applies to directories (bind-mounted copies)
 
 // go mod edit -replace github.com/PieVio/MyMarkup=../../Packages/MyMarkup
 // go mod tidy
@@ -24,7 +29,7 @@ import (
 
 const (
 	APP_NAME        = "gfind"
-	APP_VERSION     = "1.3.1"
+	APP_VERSION     = "1.5.3"
 	APP_DESCRIPTION = "Searching files in Go"
 )
 
@@ -61,7 +66,7 @@ func main() {
 	// Convert String sources into MyGlobSearch structs
 	sources := make([]*MyGlob.MyGlobSearch, len(options.sources))
 	for i, source := range options.sources {
-		mg, err := MyGlob.New(source).Autorecurse(options.autorecurse).MaxDepth(options.maxdepth).Compile()
+		mg, err := MyGlob.New(source).Autorecurse(options.autorecurse).MaxDepth(options.maxdepth).UniqueFiles(options.unique).OneFileSystem(options.xdev).Compile()
 		if err != nil {
 			fmt.Printf("*** Error building MyGlob: %v\n", err)
 			continue
@@ -135,6 +140,8 @@ func main() {
 
 	files_count := 0
 	dirs_count := 0
+	aliases_skipped := 0
+	filesystems_skipped := 0
 
 	for _, gs := range sources {
 		for ma := range gs.Explore() {
@@ -165,6 +172,8 @@ func main() {
 				}
 			}
 		}
+		aliases_skipped += gs.Stats().AliasesSkipped
+		filesystems_skipped += gs.Stats().OtherFileSystemsSkipped
 	}
 
 	duration := time.Since(start)
@@ -183,6 +192,12 @@ func main() {
 			}
 			fmt.Printf("%d dir(s)", dirs_count)
 		}
+		if aliases_skipped > 0 {
+			fmt.Printf(", %d alias(es) of already found files or directories skipped", aliases_skipped)
+		}
+		if filesystems_skipped > 0 {
+			fmt.Printf(", %d dir(s) on other file systems not explored", filesystems_skipped)
+		}
 		fmt.Printf(" found in %.3fs\n", float64(duration.Milliseconds())/1000.0)
 	}
 }
@@ -9,6 +9,8 @@ require (
 )
 
 require (
+	github.com/rivo/uniseg v0.4.7 // indirect
+	github.com/ulikunitz/xz v0.5.15 // indirect
 	golang.org/x/sys v0.33.0 // direct
 	golang.org/x/term v0.32.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
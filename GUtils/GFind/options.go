@@ -3,6 +3,10 @@
 // 2025-07-12 	PV 		First version
 // 2025-07-13 	PV 		Option -nop
 // 2025-09-07 	PV 		Option -maxdepth
+// 2026-10-19 	PV 		Option -unique
+// 2026-10-19 	PV 		Option -xdev
+// 2026-10-19 	PV 		Option --color
+// 2026-10-19 	PV 		Hidden option --help-format
 
 package main
 
@@ -25,6 +29,8 @@ type Options struct {
 	names         []string
 	maxdepth      int
 	isempty       bool
+	unique        bool
+	xdev          bool
 	recycle       bool
 	autorecurse   bool
 	noaction      bool
@@ -39,16 +45,24 @@ func header() {
 func usage() {
 	header()
 	fmt.Println()
-	text := `⌊Usage⌋: {APP_NAME} ¬[⦃?⦄|⦃-?⦄|⦃-h⦄|⦃??⦄] [⦃-v⦄] [⦃-n⦄] [⦃-f⦄|⦃-type f⦄|⦃-d⦄|⦃-type d⦄] [⦃-e⦄|⦃-empty⦄] [⦃-r+⦄|⦃-r-⦄] [⦃-a+⦄|⦃-a-⦄] [⟨action⟩...] [⦃-name⦄ ⟨name⟩] [⦃-maxdepth⦄ ⟨n⟩] ⟨source⟩...
+	MyMarkup.RenderMarkup(usageText())
+}
+
+// usageText returns usage in MyMarkup
+func usageText() string {
+	text := `⌊Usage⌋: {APP_NAME} ¬[⦃?⦄|⦃-?⦄|⦃-h⦄|⦃??⦄] [⦃-v⦄] [⦃-n⦄] [⦃-f⦄|⦃-type f⦄|⦃-d⦄|⦃-type d⦄] [⦃-e⦄|⦃-empty⦄] [⦃-u⦄|⦃-unique⦄] [⦃-xdev⦄] [⦃-r+⦄|⦃-r-⦄] [⦃-a+⦄|⦃-a-⦄] [⟨action⟩...] [⦃-name⦄ ⟨name⟩] [⦃-maxdepth⦄ ⟨n⟩] [⦃--color=⟨mode⟩⦄] ⟨source⟩...
 
 ⌊Options⌋:
 ⦃?⦄|⦃-?⦄|⦃-h⦄          ¬Show this message
 ⦃??⦄               ¬Show advanced usage notes
 ⦃-v⦄               ¬Verbose output
+⦃--color=⟨mode⟩⦄     ¬Use colors: ⦃auto⦄ (default, when output is a terminal and NO_COLOR is not set), ⦃always⦄ or ⦃never⦄
 ⦃-n⦄               ¬No action: display actions, but don't execute them
 ⦃-f⦄|⦃-type f⦄       ¬Search for files
 ⦃-d⦄|⦃-type d⦄       ¬Search for directories
 ⦃-e⦄|⦃-empty⦄        ¬Only find empty files or directories
+⦃-u⦄|⦃-unique⦄       ¬Report hard links and other aliases of a file or directory only once
+⦃-xdev⦄            ¬Don't descend into directories on other file systems than source root
 ⦃-r+⦄|⦃-r-⦄          ¬Delete to recycle bin (default) or delete forever; Recycle bin is not allowed on network sources
 ⦃-a+⦄|⦃-a-⦄          ¬Enable (default) or disable glob autorecurse mode (see extended usage)
 ⦃-name⦄ ⟨name⟩       ¬Append ⟦**/⟧⟨name⟩ to each source directory (compatibility with XFind/Search)
@@ -62,28 +76,35 @@ func usage() {
 ⦃-delete⦄          ¬Delete matching files
 ⦃-rmdir⦄           ¬Delete matching directories, whether empty or not`
 
-	MyMarkup.RenderMarkup(strings.ReplaceAll(text, "{APP_NAME}", APP_NAME))
+	return strings.ReplaceAll(text, "{APP_NAME}", APP_NAME)
 }
 
 func extendedUsage() {
 	header()
-	fmt.Println("Copyright ©2025 Pierre Violent")
-	fmt.Println()
-	MyMarkup.RenderMarkup("⌊Dependencies⌋:")
-	fmt.Println("MyGlob:", MyGlob.Version())
-	fmt.Println("TextAutoDecode:", TextAutoDecode.Version())
-	fmt.Println("MyMarkup:", MyMarkup.Version())
-	fmt.Println()
+	MyMarkup.RenderMarkup(extendedUsageText())
+}
 
+// extendedUsageText returns copyright, dependencies and advanced usage notes in MyMarkup
+func extendedUsageText() string {
 	text := `⟪⌊Advanced usage notes⌋⟫
 
 ⌊Compatibility with XFind⌋:
 - ¬Option ⦃-norecycle⦄ can be used instead of ⦃-r-⦄ to indicate to delete forever.
 - ¬Option ⦃-name⦄ can be used to indicate a specific file name or pattern to search.`
 
-	MyMarkup.RenderMarkup(strings.ReplaceAll(text, "{APP_NAME}", APP_NAME))
-	fmt.Println()
-	MyMarkup.RenderMarkup(MyGlob.GlobSyntax())
+	return "Copyright ©2025 Pierre Violent\n\n" +
+		"⌊Dependencies⌋:\n" +
+		"MyGlob: " + MyGlob.Version() + "\n" +
+		"TextAutoDecode: " + TextAutoDecode.Version() + "\n" +
+		"MyMarkup: " + MyMarkup.Version() + "\n\n" +
+		strings.ReplaceAll(text, "{APP_NAME}", APP_NAME) + "\n\n" +
+		MyGlob.GlobSyntax()
+}
+
+// helpDocument prints usage and advanced usage notes in format, for hidden option --help-format used to publish
+// documentation
+func helpDocument(format MyMarkup.OutputFormat) {
+	fmt.Print(MyMarkup.BuildDocument(format, APP_NAME, APP_VERSION, APP_DESCRIPTION, usageText()+"\n\n"+extendedUsageText()))
 }
 
 func NewOptions() (*Options, error) {
@@ -96,6 +117,24 @@ func NewOptions() (*Options, error) {
 			// Options are case insensitive
 			argls := strings.ToLower(arg[1:])
 
+			// -color=mode or --color=mode, placed before ? to get plain help
+			if name, mode, found := strings.Cut(argls, "="); found && (name == "color" || name == "-color") {
+				if err := MyMarkup.Color.Set(mode); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			// Hidden option -help-format=format or --help-format=format, to publish documentation
+			if name, format, found := strings.Cut(argls, "="); found && (name == "help-format" || name == "-help-format") {
+				var helpFormat MyMarkup.OutputFormat
+				if err := helpFormat.Set(format); err != nil {
+					return nil, err
+				}
+				helpDocument(helpFormat)
+				return nil, fmt.Errorf("")
+			}
+
 			switch argls {
 			case "?", "h", "help", "-help":
 				usage()
@@ -147,6 +186,12 @@ func NewOptions() (*Options, error) {
 			case "e", "empty":
 				opt.isempty = true
 
+			case "u", "unique":
+				opt.unique = true
+
+			case "xdev":
+				opt.xdev = true
+
 			case "r+", "recycle":
 				opt.recycle = true
 			case "r-", "norecycle":
@@ -3,24 +3,33 @@
 // 2025-08-13 	PV 		First version
 // 2025-08-18	PV 		1.1 Process files while enumerating; use MyGlob.SetChannelSize(25) to speed up globbing
 // 2025-09-22   PV      Option -v -> -t to show execution time. Option -v to invert the sense of matching, to select non-matching lines
+// 2026-10-19   PV      1.3.0 Option -xdev
+// 2026-10-19   PV      1.3.1 Use TextAutoDecode.DetectFile to skip binary files without reading them completely
+// 2026-10-19   PV      1.3.2 Decode stdin with TextAutoDecode.DecodeReader, so UTF-16 piped input is supported
+// 2026-10-19   PV      1.4.0 Option -z to search compressed files
+// 2026-10-19   PV      1.5.0 Option -b to show byte offsets in original file
+// 2026-10-19   PV      1.6.0 Files read and decoded concurrently with TextAutoDecode.DecodeFiles, while keeping output order
+// 2026-10-19   PV      1.7.0 Option --color, matches are colored according to MyMarkup color mode
+// 2026-10-19   PV      1.7.1 Hidden option --help-format to print help in html, md or man format
+// 2026-10-19   PV      1.7.2 Files decoded with a decoder configured by options, TextAutoDecode.DefaultDecoder is not modified
 
 package main
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"os"
 	"regexp"
 	"time"
 
 	"github.com/PieVio/MyGlob"
+	"github.com/PieVio/MyMarkup"
 	"github.com/PieVio/TextAutoDecode"
-	"github.com/mattn/go-isatty"
 )
 
 const (
 	APP_NAME        = "ggrep"
-	APP_VERSION     = "1.2.0"
+	APP_VERSION     = "1.7.2"
 	APP_DESCRIPTION = "Grep utility in Go"
 )
 
@@ -47,36 +56,48 @@ func main() {
 
 	start := time.Now()
 
-	// Need to wait for 2nd file to call processPath, since if there is a 2nd file, we set options.ShowPath to true
-	// to show filename before matches.  file_to_process is the file from the previous loop
-	file_to_process := ""
-	b := DataBag{}
-	for _, source := range options.Sources {
-		gs, err := MyGlob.New(source).Autorecurse(options.Autorecurse).ChannelSize(25).Compile()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s: Error building MyGlob: %v\n", APP_NAME, err)
-			continue
-		}
-
-		for ma := range gs.Explore() {
-			if ma.Err != nil {
-				if options.Verbose {
-					fmt.Fprintf(os.Stderr, "%s: error %v\n", APP_NAME, ma.Err)
-				}
+	// Files are enumerated in a goroutine and decoded concurrently by TextAutoDecode.DecodeFiles, results are
+	// delivered in enumeration order so output is deterministic
+	paths := make(chan string, 25)
+	go func() {
+		defer close(paths)
+		for _, source := range options.Sources {
+			gs, err := MyGlob.New(source).Autorecurse(options.Autorecurse).OneFileSystem(options.OneFileSystem).ChannelSize(25).Compile()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: Error building MyGlob: %v\n", APP_NAME, err)
 				continue
 			}
-			if !ma.IsDir { // We ignore matching directories in rgrep, we only look for files
-				// We've met out second file!
-				if file_to_process != "" {
-					options.ShowPath = true
-					processPath(&b, re, file_to_process, options)
+
+			for ma := range gs.Explore() {
+				if ma.Err != nil {
+					if options.Verbose {
+						fmt.Fprintf(os.Stderr, "%s: error %v\n", APP_NAME, ma.Err)
+					}
+					continue
+				}
+				if !ma.IsDir { // We ignore matching directories in rgrep, we only look for files
+					paths <- ma.Path
 				}
-				file_to_process = ma.Path
 			}
 		}
+	}()
+
+	// Need to wait for 2nd file to call processResult, since if there is a 2nd file, we set options.ShowPath to true
+	// to show filename before matches.  result_to_process is the file from the previous loop
+	decoder := options.decoder()
+	decoder.Ordered = true
+	var result_to_process *TextAutoDecode.Result
+	b := DataBag{}
+	for res := range decoder.DecodeFiles(context.Background(), paths, 0) {
+		// We've met out second file!
+		if result_to_process != nil {
+			options.ShowPath = true
+			processResult(&b, re, result_to_process, options)
+		}
+		result_to_process = &res
 	}
-	if file_to_process != "" {
-		processPath(&b, re, file_to_process, options)
+	if result_to_process != nil {
+		processResult(&b, re, result_to_process, options)
 	}
 
 	// If no source has been provided, use stdin
@@ -131,48 +152,52 @@ func processStdin(b *DataBag, re *regexp.Regexp, options *Options) error {
 		fmt.Println("Reading from stdin")
 	}
 
-	byteData, err := io.ReadAll(os.Stdin)
+	tadRes, err := options.decoder().DecodeReader(os.Stdin)
 	if err != nil {
 		return err
 	}
-	inputString := string(byteData)
+	if tadRes.Encoding == TextAutoDecode.TFE_NotText {
+		if options.Verbose {
+			fmt.Printf("%s: ignored non-text stdin\n", APP_NAME)
+		}
+		return nil
+	}
 
-	processText(b, re, inputString, "(stdin)", options)
+	processText(b, re, &tadRes, "(stdin)", options)
 	return nil
 }
 
-func processPath(b *DataBag, re *regexp.Regexp, path string, options *Options) {
-	tadRes, err := TextAutoDecode.ReadTextFile(path)
-
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "*** Error reading file %s: %v\n", path, err)
+// processResult searches a file decoded by TextAutoDecode.DecodeFiles. Binary files are only read up to the sample
+// size of the decoder, so they're skipped quickly.
+func processResult(b *DataBag, re *regexp.Regexp, res *TextAutoDecode.Result, options *Options) {
+	if res.Err != nil {
+		fmt.Fprintf(os.Stderr, "*** Error reading file %s: %v\n", res.Path, res.Err)
 		return
 	}
 
-	if tadRes.Encoding == TextAutoDecode.TFE_NotText {
+	if res.Encoding == TextAutoDecode.TFE_NotText {
 		if options.Verbose {
-			fmt.Printf("%s: ignored non-text file %s\n", APP_NAME, path)
+			fmt.Printf("%s: ignored non-text file %s\n", APP_NAME, res.Path)
 		}
-	} else {
-		// fileInfo, err := os.Stat(path)
-		// if err != nil {
-		// 	fmt.Fprintf(os.Stderr, "%s: Error getting info for file %s: %v\n", APP_NAME, path, err)
-		// 	return
-		// }
-		// if fileInfo.Size() > 1024*1024*1024 {
-		// 	if options.Verbose {
-		// 		fmt.Printf("%s: ignored very large file %s, size: %d bytes\n", APP_NAME, path, fileInfo.Size())
-		// 	}
-		// } else {
-		processText(b, re, tadRes.Text, path, options)
+		return
 	}
 
+	processText(b, re, &res.TextAutoDecode, res.Path, options)
 }
 
-func processText(b *DataBag, re *regexp.Regexp, txt, path string, options *Options) {
+func processText(b *DataBag, re *regexp.Regexp, tadRes *TextAutoDecode.TextAutoDecode, path string, options *Options) {
+	txt := tadRes.Text
 	matchlinecount := 0
 
-	if isatty.IsTerminal(os.Stdout.Fd()) {
+	// Byte offset of line in original file, "" without option -b
+	offset := func(gi GrepLineMatches) string {
+		if !options.ByteOffset {
+			return ""
+		}
+		return fmt.Sprintf("%d: ", tadRes.SourceOffset(gi.LineStart))
+	}
+
+	if MyMarkup.UseColor() {
 		// tty output in color
 		const BrightBlack string = "\033[90m"
 		const BoldRed string = "\033[1;31m"
@@ -197,11 +222,13 @@ func processText(b *DataBag, re *regexp.Regexp, txt, path string, options *Optio
 				if options.ShowPath {
 					fmt.Printf("%s%s:%s ", BrightBlack, path, NormalColor)
 				}
+				fmt.Print(offset(gi))
 				fmt.Println(gi.Line)
 			} else if options.OutLevel == 0 {
 				if options.ShowPath {
 					fmt.Printf("%s%s:%s ", BrightBlack, path, NormalColor)
 				}
+				fmt.Print(offset(gi))
 				p := 0
 				for _, ma := range gi.Ranges {
 					if ma.Start < len(gi.Line) {
@@ -234,6 +261,7 @@ func processText(b *DataBag, re *regexp.Regexp, txt, path string, options *Optio
 				if options.ShowPath {
 					fmt.Printf("%s: ", path)
 				}
+				fmt.Print(offset(gi))
 				fmt.Println(gi.Line)
 			}
 		}
@@ -2,12 +2,16 @@
 //
 // 2025-07-10 	PV 		First version
 // 2025-09-11 	PV 		TestGrepInvertIterator
+// 2026-10-19 	PV 		TestLineStart
+// 2026-10-19 	PV 		TestUsageMarkup, using MyMarkup.ValidateTexts
 
 package main
 
 import (
 	"regexp"
 	"testing"
+
+	"github.com/PieVio/MyMarkup"
 )
 
 func assert_eq[T comparable](t *testing.T, a, b T) {
@@ -71,3 +75,27 @@ Final line without the word.`
 	lm = <-ch
 	assert_eq(t, lm.Line, "Final line without the word.")
 }
+
+func TestLineStart(t *testing.T) {
+	text := "First line\r\nSecond line with été\r\nThird line\r\n"
+	re := regexp.MustCompile(`(?m)line`)
+
+	var starts []int
+	for lm := range Grep(text, re) {
+		starts = append(starts, lm.LineStart)
+	}
+	assert_eq(t, len(starts), 3)
+	assert_eq(t, starts[1], 12)
+	assert_eq(t, starts[2], 12+len("Second line with été\r\n"))
+
+	ch := GrepInvert(text, regexp.MustCompile(`(?m)First|Third`))
+	lm := <-ch
+	assert_eq(t, lm.Line, "Second line with été")
+	assert_eq(t, lm.LineStart, 12)
+}
+
+func TestUsageMarkup(t *testing.T) {
+	if err := MyMarkup.ValidateTexts(map[string]string{"usage": usageText(), "extended usage": extendedUsageText()}); err != nil {
+		t.Errorf("Invalid markup:\n%v", err)
+	}
+}
@@ -6,10 +6,11 @@ require (
 	github.com/PieVio/MyGlob v0.0.0-00010101000000-000000000000
 	github.com/PieVio/MyMarkup v0.0.0-00010101000000-000000000000
 	github.com/PieVio/TextAutoDecode v0.0.0-00010101000000-000000000000
-	github.com/mattn/go-isatty v0.0.20
 )
 
 require (
+	github.com/rivo/uniseg v0.4.7 // indirect
+	github.com/ulikunitz/xz v0.5.15 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/term v0.32.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
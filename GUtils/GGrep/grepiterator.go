@@ -4,6 +4,7 @@
 //
 // 2025-08-13   PV 		Converted from Rust by Gemini
 // 2025-09-22   PV      GrepInvert iterator to invert the sense of matching, to select non-matching lines
+// 2026-10-19   PV      LineStart offset of lines in text
 
 package main
 
@@ -22,8 +23,9 @@ type GrepMatchRange struct {
 // GrepLineMatches contains a line of text and all the regex matches found within it.
 // This is the item that will be sent over the channel.
 type GrepLineMatches struct {
-	Line   string
-	Ranges []GrepMatchRange
+	Line      string
+	LineStart int // Byte offset of the line in text
+	Ranges    []GrepMatchRange
 }
 
 // Grep iterates over a text, finds lines matching the given regular expression,
@@ -83,8 +85,9 @@ func Grep(txt string, re *regexp.Regexp) <-chan GrepLineMatches {
 				// Reset the state for the new line.
 				currentLineStartIx = lineStartIx
 				currentLineMatches = GrepLineMatches{
-					Line:   strings.TrimRight(txt[lineStartIx:lineEndIx], "\r\n"),
-					Ranges: []GrepMatchRange{},
+					Line:      strings.TrimRight(txt[lineStartIx:lineEndIx], "\r\n"),
+					LineStart: lineStartIx,
+					Ranges:    []GrepMatchRange{},
 				}
 			}
 
@@ -116,7 +119,11 @@ func GrepInvert(txt string, re *regexp.Regexp) <-chan GrepLineMatches {
 		// Split the text into lines
 		lines := strings.Split(txt, "\n")
 
+		lineStart := 0
 		for _, line := range lines {
+			start := lineStart
+			lineStart += len(line) + 1
+
 			// Trim trailing carriage return if present
 			line = strings.TrimRight(line, "\r")
 
@@ -125,8 +132,9 @@ func GrepInvert(txt string, re *regexp.Regexp) <-chan GrepLineMatches {
 				// For inverted matches, we send the whole line without specific ranges.
 				// The Ranges slice is empty.
 				ch <- GrepLineMatches{
-					Line:   line,
-					Ranges: []GrepMatchRange{},
+					Line:      line,
+					LineStart: start,
+					Ranges:    []GrepMatchRange{},
 				}
 			}
 		}
@@ -3,6 +3,12 @@
 //
 // 2025-07-10	PV 		First version
 // 2025-09-22   PV      Option -v -> -t to show execution time. Option -v to invert the sense of matching, to select non-matching lines
+// 2026-10-19   PV      Option -xdev
+// 2026-10-19   PV      Option -z
+// 2026-10-19   PV      Option -b
+// 2026-10-19   PV      Option --color
+// 2026-10-19   PV      Hidden option --help-format
+// 2026-10-19   PV      decoder configured by options, instead of modifying TextAutoDecode.DefaultDecoder
 
 package main
 
@@ -27,6 +33,9 @@ type Options struct {
 	OutLevel       int
 	ShowPath       bool 	// Set to true by main if there is more than 1 file to search from
 	Autorecurse    bool
+	OneFileSystem  bool
+	Decompress     bool
+	ByteOffset     bool
 	Verbose        bool
 }
 
@@ -41,7 +50,12 @@ func header() {
 func usage() {
 	header()
 	fmt.Println()
-	text := `⌊Usage⌋: {APP_NAME} ¬[⦃?⦄|⦃-?⦄|⦃-h⦄|⦃??⦄|⦃-??⦄] [⦃-i⦄] [⦃-w⦄] [⦃-F⦄] [⦃-v⦄] [⦃-t⦄] [⦃-c⦄] [⦃-l⦄] ⟨pattern⟩ [⟨source⟩...]
+	MyMarkup.RenderMarkup(usageText())
+}
+
+// usageText returns usage in MyMarkup
+func usageText() string {
+	text := `⌊Usage⌋: {APP_NAME} ¬[⦃?⦄|⦃-?⦄|⦃-h⦄|⦃??⦄|⦃-??⦄] [⦃-i⦄] [⦃-w⦄] [⦃-F⦄] [⦃-v⦄] [⦃-t⦄] [⦃-c⦄] [⦃-l⦄] [⦃-b⦄] [⦃-xdev⦄] [⦃-z⦄] [⦃--color=⟨mode⟩⦄] ⟨pattern⟩ [⟨source⟩...]
 
 ⌊Options⌋:
 ⦃?⦄|⦃-?⦄|⦃-h⦄  ¬Show this message
@@ -53,36 +67,48 @@ func usage() {
 ⦃-t⦄       ¬Show execution time
 ⦃-c⦄       ¬Suppress normal output, show count of matching lines for each file
 ⦃-l⦄       ¬Suppress normal output, show matching file names only
+⦃-b⦄       ¬Show byte offset in file of each output line
+⦃-xdev⦄    ¬Don't descend into directories on other file systems than source root
+⦃-z⦄       ¬Search inside gzip, bzip2 and xz compressed files
+⦃--color=⟨mode⟩⦄ ¬Use colors: ⦃auto⦄ (default, when output is a terminal and NO_COLOR is not set), ⦃always⦄ or ⦃never⦄
 ⟨pattern⟩  ¬Regular expression to search
 ⟨source⟩   ¬File or directory to search, glob syntax supported. Without source, search stdin`
 
-	MyMarkup.RenderMarkup(strings.ReplaceAll(text, "{APP_NAME}", APP_NAME))
+	return strings.ReplaceAll(text, "{APP_NAME}", APP_NAME)
 }
 
 func extendedUsage() {
 	header()
-	fmt.Println("Copyright ©2025 Pierre Violent")
-	fmt.Println()
-	MyMarkup.RenderMarkup("⌊Dependencies⌋:")
-	fmt.Println("MyGlob:", MyGlob.Version())
-	fmt.Println("TextAutoDecode:", TextAutoDecode.Version())
-	fmt.Println("MyMarkup:", MyMarkup.Version())
-	fmt.Println()
+	MyMarkup.RenderMarkup(extendedUsageText())
+}
 
+// extendedUsageText returns copyright, dependencies and advanced usage notes in MyMarkup
+func extendedUsageText() string {
 	text := `⟪⌊Advanced usage notes⌋⟫
 
 ⌊Extended options⌋:
 ⦃-a+⦄|⦃-a-⦄  ¬Enable (default) or disable glob autorecurse mode (see extended usage)
 
 Options ⦃-c⦄ (show count of matching lines) and ⦃-l⦄ (show matching file names only) can be used together to show matching lines count only for matching files.
+Offsets shown by option ⦃-b⦄ are byte offsets in the original file, including BOM, also for UTF-16 or 8-bit files. For compressed files (option ⦃-z⦄), offsets are in decompressed content.
 Put special characters such as ⟦.⟧, ⟦*⟧ or ⟦?⟧ between brackets such as ⟦[.]⟧, ⟦[*]⟧ or ⟦[?]⟧ to search them as is.
 To search for ⟦[⟧ or ⟦]⟧, use ⟦[\\[]⟧ or ⟦[\\]]⟧.
 To search for a string containing double quotes, surround string by double quotes, and double individual double quotes inside. To search for ⟦\"msg\"⟧: {APP_NAME} ⟦\"\"\"msg\"\"\"⟧ ⟦C:\\Sources\\**\\*.rs⟧
 To search for the string help, use option ⦃-F⦄: {APP_NAME} ⦃-F⦄ help ⟦C:\\Sources\\**\\*.go⟧`
 
-	MyMarkup.RenderMarkup(strings.ReplaceAll(text, "{APP_NAME}", APP_NAME))
-	fmt.Println()
-	MyMarkup.RenderMarkup(MyGlob.GlobSyntax())
+	return "Copyright ©2025 Pierre Violent\n\n" +
+		"⌊Dependencies⌋:\n" +
+		"MyGlob: " + MyGlob.Version() + "\n" +
+		"TextAutoDecode: " + TextAutoDecode.Version() + "\n" +
+		"MyMarkup: " + MyMarkup.Version() + "\n\n" +
+		strings.ReplaceAll(text, "{APP_NAME}", APP_NAME) + "\n\n" +
+		MyGlob.GlobSyntax()
+}
+
+// helpDocument prints usage and advanced usage notes in format, for hidden option --help-format used to publish
+// documentation
+func helpDocument(format MyMarkup.OutputFormat) {
+	fmt.Print(MyMarkup.BuildDocument(format, APP_NAME, APP_VERSION, APP_DESCRIPTION, usageText()+"\n\n"+extendedUsageText()))
 }
 
 func NewOptions() (*Options, error) {
@@ -106,10 +132,21 @@ func NewOptions() (*Options, error) {
 	autorecurseMinus := flag.Bool("a-", false, "Synonym for -a -")
 	flag.BoolVar(&ShowMatchCount, "c", false, "Show count of matching lines for each file")
 	flag.BoolVar(&ShowMatchPath, "l", false, "Show matching file names only")
+	flag.BoolVar(&options.ByteOffset, "b", false, "Show byte offset in file of each output line")
 	flag.BoolVar(&options.Verbose, "t", false, "Show execution time")
+	flag.BoolVar(&options.OneFileSystem, "xdev", false, "Don't descend into directories on other file systems")
+	flag.BoolVar(&options.Decompress, "z", false, "Search inside gzip, bzip2 and xz compressed files")
+	flag.Var(&MyMarkup.Color, "color", "Use colors: auto (default), always or never")
+	var helpFormat MyMarkup.OutputFormat
+	flag.Var(&helpFormat, "help-format", "Hidden option, print help in html, md or man format")
 
 	flag.Parse()
 
+	if helpFormat != MyMarkup.OF_Terminal {
+		helpDocument(helpFormat)
+		os.Exit(0)
+	}
+
 	if *showHelp || *showHelp2 || flag.NArg() > 0 && (flag.Args()[0] == "?" || flag.Args()[0] == "help") {
 		usage()
 		os.Exit(0)
@@ -165,3 +202,12 @@ func NewOptions() (*Options, error) {
 
 	return options, nil
 }
+
+// decoder returns a copy of TextAutoDecode.DefaultDecoder configured according to options, so the package global is
+// never modified
+func (options *Options) decoder() *TextAutoDecode.Decoder {
+	d := TextAutoDecode.DefaultDecoder
+	// With -z, gzip, bzip2 and xz files are searched after decompression, -b offsets are in decompressed content
+	d.Decompress = options.Decompress
+	return &d
+}
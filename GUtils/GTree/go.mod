@@ -9,6 +9,9 @@ require (
 	golang.org/x/sys v0.33.0
 )
 
-require golang.org/x/term v0.32.0 // indirect
+require (
+	github.com/rivo/uniseg v0.4.7 // indirect
+	golang.org/x/term v0.32.0 // indirect
+)
 
 replace github.com/PieVio/MyMarkup => ../../Packages/MyMarkup
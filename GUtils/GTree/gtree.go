@@ -10,6 +10,9 @@
 // 2025-07-02	PV		1.2.1 Usage using MyMarkup
 // 2025-07-02	PV		1.2.2 Print links
 // 2025-07-03	PV		1.3.0 Junctions, use sortmethod, maxdepth
+// 2026-10-19	PV		1.4.0 Option -xdev
+// 2026-10-19	PV		1.4.1 Option --color for usage
+// 2026-10-19	PV		1.4.2 Hidden option --help-format to print help in html, md or man format
 
 package main
 
@@ -34,10 +37,13 @@ var sortmethod int
 var sortmethod1 bool
 var sortmethod2 bool
 var maxdepth int
+var xdev bool
+var root_device uint64
+var check_device bool
 
 // Global constants
 const APP_NAME string = "gtree"
-const APP_VERSION string = "1.3.0"
+const APP_VERSION string = "1.4.2"
 const APP_DESCRIPTION = "Visual directory structure in Go"
 
 func header() {
@@ -49,8 +55,12 @@ func header() {
 func usage() {
 	header()
 	fmt.Println()
-	
-	text := `⌊Usage⌋: {APP_NAME} ¬[⦃?⦄|⦃-?⦄|⦃-h⦄|⦃??⦄|⦃-??⦄] [-⦃a⦄|-⦃A⦄] [-⦃s⦄ ⦃0⦄|⦃1⦄|⦃2⦄] [⦃-d⦄ ⟨max_depth⟩] [-⦃v⦄] [⟨dir⟩]
+	MyMarkup.RenderMarkup(usageText())
+}
+
+// usageText returns usage in MyMarkup
+func usageText() string {
+	text := `⌊Usage⌋: {APP_NAME} ¬[⦃?⦄|⦃-?⦄|⦃-h⦄|⦃??⦄|⦃-??⦄] [-⦃a⦄|-⦃A⦄] [-⦃s⦄ ⦃0⦄|⦃1⦄|⦃2⦄] [⦃-d⦄ ⟨max_depth⟩] [⦃-xdev⦄] [-⦃v⦄] [⦃--color=⟨mode⟩⦄] [⟨dir⟩]
 
 ⌊Options⌋:
 ⦃?⦄|⦃-?⦄|⦃-h⦄      ¬Show this message
@@ -59,20 +69,21 @@ func usage() {
 ⦃-A⦄           ¬Show system+hidden directories and directories starting with a dollar sign
 ⦃-s⦄ ⦃0⦄|⦃1⦄|⦃2⦄     ¬Sort method: 0=Default, 1=Windows File Explorer (Windows only), 2=Case fold
 ⦃-d⦄ ⟨max_depth⟩ ¬Limits recursion to max_depth folders, default is 0 meaning no limitation
+⦃-xdev⦄        ¬Don't descend into directories on other file systems than starting directory
 ⦃-v⦄           ¬Verbose output
+⦃--color=⟨mode⟩⦄ ¬Use colors: ⦃auto⦄ (default, when output is a terminal and NO_COLOR is not set), ⦃always⦄ or ⦃never⦄
 ⟨dir⟩          ¬Starting directory`
 
-	MyMarkup.RenderMarkup(strings.Replace(text, "{APP_NAME}", APP_NAME, -1))
+	return strings.ReplaceAll(text, "{APP_NAME}", APP_NAME)
 }
 
 func extendedUsage() {
 	header()
-	fmt.Println("Copyright ©2025 Pierre Violent")
-	fmt.Println()
-	MyMarkup.RenderMarkup("⌊Dependencies⌋:")
-	fmt.Println("MyMarkup:", MyMarkup.Version())
-	fmt.Println()
-	
+	MyMarkup.RenderMarkup(extendedUsageText())
+}
+
+// extendedUsageText returns copyright, dependencies and advanced usage notes in MyMarkup
+func extendedUsageText() string {
 	text := `⟪⌊Advanced usage notes⌋⟫
 	
 By default, hidden folders are not shown.
@@ -85,15 +96,28 @@ When recursion depth is limited using option ⦃-d⦄, "..." at the end of the f
 
 Regardless of recursion depth limitation, "... ?" at the end of a folder means that folder content access is denied, so it is unknown if there are subfolders or not.
 
+With option ⦃-xdev⦄, folders on another file system than starting directory (mount points, ⟦/proc⟧, network drives...) are shown followed by [other file system], but are not explored.
+
 Option ⦃-v⦄ show small statistics at the end of tree.
 `
-	MyMarkup.RenderMarkup(strings.ReplaceAll(text, "{APP_NAME}", APP_NAME))
+
+	return "Copyright ©2025 Pierre Violent\n\n" +
+		"⌊Dependencies⌋:\n" +
+		"MyMarkup: " + MyMarkup.Version() + "\n\n" +
+		strings.ReplaceAll(text, "{APP_NAME}", APP_NAME)
+}
+
+// helpDocument prints usage and advanced usage notes in format, for hidden option --help-format used to publish
+// documentation
+func helpDocument(format MyMarkup.OutputFormat) {
+	fmt.Print(MyMarkup.BuildDocument(format, APP_NAME, APP_VERSION, APP_DESCRIPTION, usageText()+"\n\n"+extendedUsageText()))
 }
 
 type DataBag = struct {
 	DirCount      int
 	SymLinkDCount int
 	JunctionCount int
+	OtherFSCount  int
 }
 
 // func main() {
@@ -112,10 +136,19 @@ func main() {
 	flag.BoolVar(&sortmethod1, "s1", false, "Sort method 1")
 	flag.BoolVar(&sortmethod2, "s2", false, "Sort method 2")
 	flag.IntVar(&maxdepth, "d", 0, "Max recursion depth, 0=no limit")
+	flag.BoolVar(&xdev, "xdev", false, "Don't descend into directories on other file systems")
+	flag.Var(&MyMarkup.Color, "color", "Use colors: auto (default), always or never")
+	var helpFormat MyMarkup.OutputFormat
+	flag.Var(&helpFormat, "help-format", "Hidden option, print help in html, md or man format")
 
 	flag.Usage = usage
 	flag.Parse()
 
+	if helpFormat != MyMarkup.OF_Terminal {
+		helpDocument(helpFormat)
+		os.Exit(0)
+	}
+
 	// First process help
 	if h1 || h2 || flag.NArg() > 0 && (flag.Args()[0] == "?" || flag.Args()[0] == "help") {
 		flag.Usage()
@@ -149,6 +182,10 @@ func main() {
 		root = "."
 	}
 
+	if xdev {
+		root_device, check_device = get_device(root)
+	}
+
 	b := DataBag{}
 	start := time.Now()
 	doPrint(&b, root, maxdepth)
@@ -162,6 +199,9 @@ func main() {
 		if b.JunctionCount > 0 {
 			fmt.Printf(", %d Junction(s)", b.JunctionCount)
 		}
+		if b.OtherFSCount > 0 {
+			fmt.Printf(", %d on other file system(s)", b.OtherFSCount)
+		}
 		fmt.Printf(" in %.3fs\n", duration.Seconds())
 	}
 }
@@ -250,6 +290,14 @@ func printTree(b *DataBag, root string, subdir DirEntryData, prefix string, is_l
 	b.DirCount++
 
 	subdir_fp := filepath.Join(root, subdir.Name)
+	if check_device {
+		if device, ok := get_device(subdir_fp); ok && device != root_device {
+			fmt.Println("  [other file system]")
+			b.OtherFSCount++
+			return
+		}
+	}
+
 	entries, err := os.ReadDir(subdir_fp)
 	if err != nil {
 		fmt.Println("  ... ?")
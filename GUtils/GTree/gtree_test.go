@@ -0,0 +1,17 @@
+// Tests for GTree
+//
+// 2026-10-19	PV		First version, TestUsageMarkup
+
+package main
+
+import (
+	"testing"
+
+	"github.com/PieVio/MyMarkup"
+)
+
+func TestUsageMarkup(t *testing.T) {
+	if err := MyMarkup.ValidateTexts(map[string]string{"usage": usageText(), "extended usage": extendedUsageText()}); err != nil {
+		t.Errorf("Invalid markup:\n%v", err)
+	}
+}
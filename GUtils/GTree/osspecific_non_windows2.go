@@ -4,12 +4,14 @@
 // Non-windows specific code
 //
 // 2025-07-02	PV 		First version, also first example of os-specific compilation
+// 2026-10-19	PV 		get_device for option -xdev
 
 package main
 
 import (
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	"golang.org/x/text/cases"
 )
@@ -30,3 +32,11 @@ func path_comparer(_sortmethod int, s1, s2 string) int {
 	return strings.Compare(str1, str2)
 }
 
+// get_device returns the device ID of the file system containing path
+func get_device(path string) (uint64, bool) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
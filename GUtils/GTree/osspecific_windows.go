@@ -2,6 +2,7 @@
 // Windows-specific code
 //
 // 2025-07-02	PV 		First version, also first example of os-specific compilation
+// 2026-10-19	PV 		get_device for option -xdev
 
 //go:build windows
 
@@ -41,6 +42,27 @@ func is_hidden_folder(path string) (bool, bool) {
 	return isHidden, isHidden && isSystem
 }
 
+// get_device returns the volume serial number of the volume containing path
+func get_device(path string) (uint64, bool) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, false
+	}
+
+	// FILE_FLAG_BACKUP_SEMANTICS is required to get a handle on a directory
+	h, err := syscall.CreateFile(pathPtr, 0, syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE, nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return 0, false
+	}
+	defer syscall.CloseHandle(h)
+
+	var fi syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(h, &fi); err != nil {
+		return 0, false
+	}
+	return uint64(fi.VolumeSerialNumber), true
+}
+
 // Define the signature of StrCmpLogicalW
 // int StrCmpLogicalW(LPCWSTR psz1, LPCWSTR psz2);
 // Returns:
@@ -3,6 +3,21 @@
 //
 // 2025-07-05 	PV 		Initial translation by Gemini
 // 2025-07-07 	PV 		1.03 Compact options -a+ and -a-
+// 2026-10-19 	PV 		1.1.0 Use TextAutoDecode.DetectFile to skip binary files without reading them completely
+// 2026-10-19 	PV 		1.1.1 Decode stdin directly with TextAutoDecode.DecodeReader instead of copying it to a temp file
+// 2026-10-19 	PV 		1.2.0 UTF-32 files
+// 2026-10-19 	PV 		1.2.1 Show code page of 8-bit files
+// 2026-10-19 	PV 		1.3.0 CJK legacy encodings Shift_JIS, GB18030, Big5 and EUC-KR
+// 2026-10-19 	PV 		1.3.1 Warning when charset declared in file doesn't match its content
+// 2026-10-19 	PV 		1.3.2 EOL styles from TextAutoDecode line statistics instead of scanning text again
+// 2026-10-19 	PV 		1.4.0 Unicode files with a few invalid sequences are reported as such instead of 8-bit or non-text
+// 2026-10-19 	PV 		1.4.1 Format of non-text files with a text extension (ZIP, PNG...) shown in warning
+// 2026-10-19 	PV 		1.5.0 Option -z to analyze compressed files, compression format shown after encoding
+// 2026-10-19 	PV 		1.5.1 Option --color, warnings colored according to MyMarkup color mode
+// 2026-10-19 	PV 		1.5.2 Hidden option --help-format to print help in html, md or man format
+// 2026-10-19 	PV 		1.5.3 Files are only rejected before decoding when their magic number is a binary format, invalid Unicode sequences are reported as for stdin
+// 2026-10-19 	PV 		1.5.4 Files are read once, without a DetectFile pre-pass, since ReadTextFile stops early on non-text files
+// 2026-10-19 	PV 		1.5.5 Files decoded with a decoder configured by options, TextAutoDecode.DefaultDecoder is not modified
 
 /*
 I need to translate a simple command line Rust program into its equivalent in Go.
@@ -35,7 +50,6 @@ package main
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -43,13 +57,14 @@ import (
 	"time"
 
 	"github.com/PieVio/MyGlob"
+	"github.com/PieVio/MyMarkup"
 	"github.com/PieVio/TextAutoDecode"
 	"github.com/fatih/color"
 )
 
 const (
 	APP_NAME        = "gtt"
-	APP_VERSION     = "1.0.3"
+	APP_VERSION     = "1.5.5"
 	APP_DESCRIPTION = "Text type information in Go"
 )
 
@@ -96,7 +111,9 @@ type FileTypeCounts struct {
 	Ascii    int
 	Utf8     int
 	Utf16    int
+	Utf32    int
 	EightBit int
+	Cjk      int
 	NonText  int
 }
 
@@ -119,6 +136,9 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Same color choice as usage: --color, NO_COLOR, TERM and terminal output
+	color.NoColor = !MyMarkup.UseColor()
+
 	start := time.Now()
 
 	b := NewDataBag()
@@ -138,7 +158,7 @@ func main() {
 				continue
 			}
 			if !ma.IsDir { // We ignore matching directories in rgrep, we only look for files
-				printResult(processFile(b, ma.Path, ma.Path), options)
+				printResult(processFile(b, ma.Path, ma.Path, options), options)
 			}
 		}
 	}
@@ -164,7 +184,9 @@ func main() {
 				filePrinted := false
 
 				ft := b.Counters[f][e].FilesTypes
-				if (ft.Utf8 > 0 && ft.Utf16 > 0) || (ft.Utf8 > 0 && ft.EightBit > 0) || (ft.Utf16 > 0 && ft.Ascii > 0) || (ft.Utf16 > 0 && ft.EightBit > 0) {
+				if (ft.Utf8 > 0 && ft.Utf16 > 0) || (ft.Utf8 > 0 && ft.EightBit > 0) || (ft.Utf16 > 0 && ft.Ascii > 0) || (ft.Utf16 > 0 && ft.EightBit > 0) ||
+					(ft.Utf32 > 0 && (ft.Ascii > 0 || ft.Utf8 > 0 || ft.Utf16 > 0 || ft.EightBit > 0)) ||
+					(ft.Cjk > 0 && (ft.Utf8 > 0 || ft.Utf16 > 0 || ft.Utf32 > 0 || ft.EightBit > 0)) {
 					if !headerPrinted {
 						fmt.Println("\nMixed directory contents:")
 						headerPrinted = true
@@ -221,25 +243,13 @@ func processStdin(b *DataBag, options *Options) error {
 		fmt.Println("Reading from stdin")
 	}
 
-	tempFile, err := os.CreateTemp("", "rtt-stdin-")
-	if err != nil {
-		return err
-	}
-	defer os.Remove(tempFile.Name())
-	defer tempFile.Close()
-
-	_, err = io.Copy(tempFile, os.Stdin)
-	if err != nil {
-		return err
-	}
-	tempFile.Sync()
-
-	printResult(processFile(b, tempFile.Name(), "(stdin)"), options)
+	tadRes, err := options.decoder().DecodeReader(os.Stdin)
+	printResult(processDecoded(b, tadRes, err, "(stdin)"), options)
 	return nil
 }
 
 func printFilesTypesCounts(f FileTypeCounts) {
-	tot := f.Empty + f.Ascii + f.Utf8 + f.Utf16 + f.EightBit + f.NonText
+	tot := f.Empty + f.Ascii + f.Utf8 + f.Utf16 + f.Utf32 + f.EightBit + f.Cjk + f.NonText
 	fmt.Printf("Total files: %d\n", tot)
 	if f.Empty > 0 {
 		fmt.Printf("- Empty: %d\n", f.Empty)
@@ -253,9 +263,15 @@ func printFilesTypesCounts(f FileTypeCounts) {
 	if f.Utf16 > 0 {
 		fmt.Printf("- UTF-16: %d\n", f.Utf16)
 	}
+	if f.Utf32 > 0 {
+		fmt.Printf("- UTF-32: %d\n", f.Utf32)
+	}
 	if f.EightBit > 0 {
 		fmt.Printf("- 8-Bit: %d\n", f.EightBit)
 	}
+	if f.Cjk > 0 {
+		fmt.Printf("- CJK: %d\n", f.Cjk)
+	}
 	if f.NonText > 0 {
 		fmt.Printf("- Non text: %d\n", f.NonText)
 	}
@@ -311,9 +327,16 @@ func printResultCore(msg string) {
 	}
 }
 
-func processFile(b *DataBag, pathForRead string, pathForName string) string {
-	tadRes, err := TextAutoDecode.ReadTextFile(pathForRead)
+func processFile(b *DataBag, pathForRead string, pathForName string, options *Options) string {
+	// Files are decoded with the lenient policy, so that a Unicode file with a few invalid sequences gets the same
+	// verdict as when read from stdin. Non-text files are rejected after reading only their beginning.
+	tadRes, err := options.decoder().ReadTextFile(pathForRead)
+
+	return processDecoded(b, tadRes, err, pathForName)
+}
 
+// processDecoded updates statistics and returns the message to print for a decoded file or stdin
+func processDecoded(b *DataBag, tadRes TextAutoDecode.TextAutoDecode, err error, pathForName string) string {
 	b.FilesTypes.Total++
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "*** Error reading file %s: %v\n", pathForName, err)
@@ -351,6 +374,9 @@ func processFile(b *DataBag, pathForRead string, pathForName string) string {
 			}
 		}
 		if isTextExt {
+			if tadRes.Kind != TextAutoDecode.FK_Unknown {
+				return fmt.Sprintf("%s: «%s file detected, but extension %s is usually a text file»", pathForName, tadRes.Kind, ext)
+			}
 			return fmt.Sprintf("%s: «Non-text file detected, but extension %s is usually a text file»", pathForName, ext)
 		}
 		return ""
@@ -371,6 +397,9 @@ func processFile(b *DataBag, pathForRead string, pathForName string) string {
 		b.FilesTypes.EightBit++
 		fc.FilesTypes.EightBit++
 		enc = "8-Bit text"
+		if tadRes.CodePage != "" {
+			enc += " (" + tadRes.CodePage + ")"
+		}
 		war = ""
 
 	case TextAutoDecode.TFE_UTF8, TextAutoDecode.TFE_UTF8BOM:
@@ -396,9 +425,55 @@ func processFile(b *DataBag, pathForRead string, pathForName string) string {
 		} else {
 			war = ""
 		}
+
+	case TextAutoDecode.TFE_UTF32LE, TextAutoDecode.TFE_UTF32BE, TextAutoDecode.TFE_UTF32LEBOM, TextAutoDecode.TFE_UTF32BEBOM:
+		b.FilesTypes.Utf32++
+		fc.FilesTypes.Utf32++
+		if tadRes.Encoding == TextAutoDecode.TFE_UTF32LE || tadRes.Encoding == TextAutoDecode.TFE_UTF32LEBOM {
+			enc = "UTF-32 LE"
+		} else {
+			enc = "UTF-32 BE"
+		}
+		if tadRes.Encoding == TextAutoDecode.TFE_UTF32LE || tadRes.Encoding == TextAutoDecode.TFE_UTF32BE {
+			war = "without BOM"
+		} else {
+			war = ""
+		}
+
+	case TextAutoDecode.TFE_ShiftJIS, TextAutoDecode.TFE_GB18030, TextAutoDecode.TFE_Big5, TextAutoDecode.TFE_EUCKR:
+		b.FilesTypes.Cjk++
+		fc.FilesTypes.Cjk++
+		switch tadRes.Encoding {
+		case TextAutoDecode.TFE_ShiftJIS:
+			enc = "Shift_JIS"
+		case TextAutoDecode.TFE_GB18030:
+			enc = "GB18030"
+		case TextAutoDecode.TFE_Big5:
+			enc = "Big5"
+		default:
+			enc = "EUC-KR"
+		}
+		war = ""
 	}
 
-	eol := getEol(tadRes.Text)
+	if tadRes.Container != TextAutoDecode.FK_Unknown {
+		enc += fmt.Sprintf(" (%s)", tadRes.Container)
+	}
+
+	if tadRes.Source == TextAutoDecode.TES_Conflict {
+		if war != "" {
+			war += ", "
+		}
+		war += fmt.Sprintf("declared charset %s doesn't match content", tadRes.DeclaredCharset)
+	}
+	if tadRes.InvalidCount > 0 {
+		if war != "" {
+			war += ", "
+		}
+		war += invalidWarning(tadRes)
+	}
+
+	eol := getEol(tadRes.Lines)
 
 	fc.EolStyles.Windows += eol.Windows
 	fc.EolStyles.Unix += eol.Unix
@@ -433,22 +508,38 @@ func processFile(b *DataBag, pathForRead string, pathForName string) string {
 	return res
 }
 
-func getEol(txt string) EOLStyleCounts {
+// invalidWarning describes invalid sequences replaced while decoding, such as "2 invalid bytes at offsets 1234, 1500"
+func invalidWarning(tadRes TextAutoDecode.TextAutoDecode) string {
+	const maxShown = 5
+
+	unit := "sequence"
+	if tadRes.Encoding == TextAutoDecode.TFE_UTF8 || tadRes.Encoding == TextAutoDecode.TFE_UTF8BOM {
+		unit = "byte"
+	}
+	var offsets []string
+	for _, offset := range tadRes.InvalidOffsets[:min(len(tadRes.InvalidOffsets), maxShown)] {
+		offsets = append(offsets, fmt.Sprint(offset))
+	}
+	if tadRes.InvalidCount > maxShown {
+		offsets = append(offsets, "...")
+	}
+
+	if tadRes.InvalidCount == 1 {
+		return fmt.Sprintf("1 invalid %s at offset %s", unit, offsets[0])
+	}
+	return fmt.Sprintf("%d invalid %ss at offsets %s", tadRes.InvalidCount, unit, strings.Join(offsets, ", "))
+}
+
+func getEol(lines TextAutoDecode.LineStats) EOLStyleCounts {
 	eol := EOLStyleCounts{}
-	bytes := []byte(txt)
-	for i := 0; i < len(bytes); i++ {
-		c := bytes[i]
-		switch c {
-		case '\n':
-			eol.Unix = 1
-		case '\r':
-			if i+1 < len(bytes) && bytes[i+1] == '\n' {
-				i++
-				eol.Windows = 1
-			} else {
-				eol.Mac = 1
-			}
-		}
+	if lines.LF > 0 {
+		eol.Unix = 1
+	}
+	if lines.CRLF > 0 {
+		eol.Windows = 1
+	}
+	if lines.CR > 0 {
+		eol.Mac = 1
 	}
 
 	// Don't count files without EOL detected in total
@@ -456,8 +547,7 @@ func getEol(txt string) EOLStyleCounts {
 		eol.Total++
 	}
 
-	// Helper
-	if eol.Windows+eol.Unix+eol.Mac > 1 {
+	if lines.Mixed {
 		eol.Mixed = 1
 	}
 
@@ -1,12 +1,24 @@
 // Tests for Gtt
 //
 // 2025-07-05 	PV 		Translation of Rust equivalent by Gemini
+// 2026-10-19 	PV 		TestUtf32bebom
+// 2026-10-19 	PV 		TestShiftJIS
+// 2026-10-19 	PV 		TestDeclaredConflict
+// 2026-10-19 	PV 		TestInvalidUtf8
+// 2026-10-19 	PV 		TestZipAsText
+// 2026-10-19 	PV 		TestGzip
+// 2026-10-19 	PV 		TestUsageMarkup, using MyMarkup.ValidateTexts
+// 2026-10-19 	PV 		TestUtf16LoneSurrogate
+// 2026-10-19 	PV 		processFile takes options, TestGzip no longer modifies TextAutoDecode.DefaultDecoder
 
 package main
 
 import (
+	"compress/gzip"
 	"os"
 	"testing"
+
+	"github.com/PieVio/MyMarkup"
 )
 
 func TestEmpty(t *testing.T) {
@@ -24,7 +36,7 @@ func TestEmpty(t *testing.T) {
 	tempFile.Sync()
 
 	b := NewDataBag()
-	res := processFile(b, tempFile.Name(), "(test empty)")
+	res := processFile(b, tempFile.Name(), "(test empty)", &Options{})
 
 	if res != "(test empty): «Empty file»" {
 		t.Errorf("Expected \"(test empty): «Empty file»\", got \"%s\"", res)
@@ -84,7 +96,7 @@ func TestAscii(t *testing.T) {
 	tempFile.Sync()
 
 	b := NewDataBag()
-	res := processFile(b, tempFile.Name(), "(test ascii)")
+	res := processFile(b, tempFile.Name(), "(test ascii)", &Options{})
 
 	if res != "(test ascii): ASCII, Windows" {
 		t.Errorf("Expected \"(test ascii): ASCII, Windows\", got \"%s\"", res)
@@ -144,7 +156,7 @@ func TestNonText1(t *testing.T) {
 	tempFile.Sync()
 
 	b := NewDataBag()
-	res := processFile(b, tempFile.Name(), "(test non-text)")
+	res := processFile(b, tempFile.Name(), "(test non-text)", &Options{})
 
 	if res != "" {
 		t.Errorf("Expected \"\", got \"%s\"", res)
@@ -204,7 +216,7 @@ func TestNonText2(t *testing.T) {
 	tempFile.Sync()
 
 	b := NewDataBag()
-	res := processFile(b, tempFile.Name(), "non-text.rs")
+	res := processFile(b, tempFile.Name(), "non-text.rs", &Options{})
 
 	if res != "non-text.rs: «Non-text file detected, but extension rs is usually a text file»" {
 		t.Errorf("Expected \"non-text.rs: «Non-text file detected, but extension rs is usually a text file»\", got \"%s\"", res)
@@ -273,7 +285,7 @@ func TestUtf8(t *testing.T) {
 	tempFile.Sync()
 
 	b := NewDataBag()
-	res := processFile(b, tempFile.Name(), "(test utf8)")
+	res := processFile(b, tempFile.Name(), "(test utf8)", &Options{})
 
 	if res != "(test utf8): UTF-8, No EOL detected" {
 		t.Errorf("Expected \"(test utf8): UTF-8, No EOL detected\", got \"%s\"", res)
@@ -339,7 +351,7 @@ func TestUtf8Bom(t *testing.T) {
 	tempFile.Sync()
 
 	b := NewDataBag()
-	res := processFile(b, tempFile.Name(), "(test utf8bom)")
+	res := processFile(b, tempFile.Name(), "(test utf8bom)", &Options{})
 
 	if res != "(test utf8bom): UTF-8 «with BOM», Mac" {
 		t.Errorf("Expected \"(test utf8bom): UTF-8 «with BOM», Mac\", got \"%s\"", res)
@@ -409,7 +421,7 @@ func TestUtf16LeBom(t *testing.T) {
 	tempFile.Sync()
 
 	b := NewDataBag()
-	res := processFile(b, tempFile.Name(), "(test utf16lebom)")
+	res := processFile(b, tempFile.Name(), "(test utf16lebom)", &Options{})
 
 	if res != "(test utf16lebom): UTF-16 LE, «Mixed EOL styles»" {
 		t.Errorf("Expected \"(test utf16lebom): UTF-16 LE, «Mixed EOL styles»\", got \"%s\"", res)
@@ -484,7 +496,7 @@ func TestUtf16Le1(t *testing.T) {
 	tempFile.Sync()
 
 	b := NewDataBag()
-	res := processFile(b, tempFile.Name(), "(test utf16le1)")
+	res := processFile(b, tempFile.Name(), "(test utf16le1)", &Options{})
 
 	if res != "(test utf16le1): UTF-16 LE «without BOM», Unix" {
 		t.Errorf("Expected \"(test utf16le1): UTF-16 LE «without BOM», Unix\", got \"%s\"", res)
@@ -551,7 +563,7 @@ func TestUtf16Le2(t *testing.T) {
 	tempFile.Sync()
 
 	b := NewDataBag()
-	res := processFile(b, tempFile.Name(), "(test utf16le1)")
+	res := processFile(b, tempFile.Name(), "(test utf16le1)", &Options{})
 
 	if res != "" {
 		t.Errorf("Expected \"\", got \"%s\"", res)
@@ -595,3 +607,235 @@ func TestUtf16Le2(t *testing.T) {
 		t.Errorf("Expected EolStyles.Mixed 0, got %d", b.EolStyles.Mixed)
 	}
 }
+
+func TestUtf32bebom(t *testing.T) {
+	model := []byte{
+		0x00, 0x00, 0xFE, 0xFF, // BOM
+		0x00, 0x00, 0x00, 0x41, // A
+		0x00, 0x01, 0xF4, 0x17, // 🐗
+		0x00, 0x00, 0x00, '\r', 0x00, 0x00, 0x00, '\n', // Windows EOL
+		0x00, 0x00, 0x00, 0x61, // a
+		0x00, 0x00, 0x00, '\r', 0x00, 0x00, 0x00, '\n', // Windows EOL
+	}
+
+	tempFile, err := os.CreateTemp("", "rtt-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	_, err = tempFile.Write(model)
+	if err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tempFile.Sync()
+
+	b := NewDataBag()
+	res := processFile(b, tempFile.Name(), "(test utf32bebom)", &Options{})
+
+	if res != "(test utf32bebom): UTF-32 BE, Windows" {
+		t.Errorf("Expected \"(test utf32bebom): UTF-32 BE, Windows\", got \"%s\"", res)
+	}
+
+	if b.FilesTypes.Total != 1 {
+		t.Errorf("Expected FilesTypes.Total 1, got %d", b.FilesTypes.Total)
+	}
+	if b.FilesTypes.Utf16 != 0 {
+		t.Errorf("Expected FilesTypes.Utf16 0, got %d", b.FilesTypes.Utf16)
+	}
+	if b.FilesTypes.Utf32 != 1 {
+		t.Errorf("Expected FilesTypes.Utf32 1, got %d", b.FilesTypes.Utf32)
+	}
+	if b.FilesTypes.NonText != 0 {
+		t.Errorf("Expected FilesTypes.NonText 0, got %d", b.FilesTypes.NonText)
+	}
+
+	if b.EolStyles.Windows != 1 {
+		t.Errorf("Expected EolStyles.Windows 1, got %d", b.EolStyles.Windows)
+	}
+}
+
+func TestShiftJIS(t *testing.T) {
+	model := []byte{
+		0x82, 0xB1, 0x82, 0xEA, 0x82, 0xCD, 0x8E, 0x84, 0x82, 0xCC, 0x96, 0x7B, 0x82, 0xC5, 0x82, 0xB7, 0x81, 0x42, // これは私の本です。
+		0x0D, 0x0A, // Windows EOL
+		0x8D, 0xA1, 0x93, 0xFA, 0x82, 0xCD, 0x82, 0xA2, 0x82, 0xA2, 0x93, 0x56, 0x8B, 0x43, 0x82, 0xC5, 0x82, 0xB7, 0x82, 0xCB, 0x81, 0x42, // 今日はいい天気ですね。
+		0x0D, 0x0A, // Windows EOL
+	}
+
+	tempFile, err := os.CreateTemp("", "rtt-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	_, err = tempFile.Write(model)
+	if err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tempFile.Sync()
+
+	b := NewDataBag()
+	res := processFile(b, tempFile.Name(), "(test shiftjis)", &Options{})
+
+	if res != "(test shiftjis): Shift_JIS, Windows" {
+		t.Errorf("Expected \"(test shiftjis): Shift_JIS, Windows\", got \"%s\"", res)
+	}
+
+	if b.FilesTypes.Cjk != 1 {
+		t.Errorf("Expected FilesTypes.Cjk 1, got %d", b.FilesTypes.Cjk)
+	}
+	if b.FilesTypes.EightBit != 0 {
+		t.Errorf("Expected FilesTypes.EightBit 0, got %d", b.FilesTypes.EightBit)
+	}
+	if b.EolStyles.Windows != 1 {
+		t.Errorf("Expected EolStyles.Windows 1, got %d", b.EolStyles.Windows)
+	}
+}
+
+func TestDeclaredConflict(t *testing.T) {
+	model := []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<p>Café</p>\n")
+
+	tempFile, err := os.CreateTemp("", "rtt-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	_, err = tempFile.Write(model)
+	if err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tempFile.Sync()
+
+	b := NewDataBag()
+	res := processFile(b, tempFile.Name(), "(test conflict)", &Options{})
+
+	expected := "(test conflict): UTF-8 «declared charset ISO-8859-1 doesn't match content», Unix"
+	if res != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, res)
+	}
+}
+
+func TestInvalidUtf8(t *testing.T) {
+	model := []byte("Desserts of the day, served with coffee or tea:\nCrème brûlée \xff\nCafé \xfe\xfd\n")
+
+	tempFile, err := os.CreateTemp("", "rtt-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	_, err = tempFile.Write(model)
+	if err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tempFile.Sync()
+
+	b := NewDataBag()
+	res := processFile(b, tempFile.Name(), "(test invalid)", &Options{})
+
+	expected := "(test invalid): UTF-8 «3 invalid bytes at offsets 64, 72, 73», Unix"
+	if res != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, res)
+	}
+	if b.FilesTypes.Utf8 != 1 {
+		t.Errorf("Expected FilesTypes.Utf8 1, got %d", b.FilesTypes.Utf8)
+	}
+}
+
+func TestZipAsText(t *testing.T) {
+	model := append([]byte("PK\x03\x04\x14\x00\x00\x00\x08\x00"), make([]byte, 30)...)
+
+	tempFile, err := os.CreateTemp("", "rtt-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	_, err = tempFile.Write(model)
+	if err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tempFile.Sync()
+
+	b := NewDataBag()
+	res := processFile(b, tempFile.Name(), "notes.txt", &Options{})
+
+	expected := "notes.txt: «ZIP file detected, but extension txt is usually a text file»"
+	if res != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, res)
+	}
+	if b.FilesTypes.NonText != 1 {
+		t.Errorf("Expected FilesTypes.NonText 1, got %d", b.FilesTypes.NonText)
+	}
+}
+
+func TestGzip(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "rtt-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	zw := gzip.NewWriter(tempFile)
+	_, err = zw.Write([]byte("Service started\nService stopped\n"))
+	if err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	zw.Close()
+	tempFile.Sync()
+
+	// Option -z
+	b := NewDataBag()
+	res := processFile(b, tempFile.Name(), "app.log.1.gz", &Options{Decompress: true})
+
+	expected := "app.log.1.gz: ASCII (gzip), Unix"
+	if res != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, res)
+	}
+	if b.FilesTypes.Ascii != 1 {
+		t.Errorf("Expected FilesTypes.Ascii 1, got %d", b.FilesTypes.Ascii)
+	}
+}
+
+func TestUtf16LoneSurrogate(t *testing.T) {
+	// UTF-16 LE with BOM, unpaired high surrogate at offset 14: same verdict as stdin, not a non-text file
+	model := []byte{0xFF, 0xFE, 'H', 0, 'e', 0, 'l', 0, 'l', 0, 'o', 0, ' ', 0, 0x00, 0xD8, 'w', 0, 'o', 0, 'r', 0, 'l', 0, 'd', 0, '\n', 0}
+
+	tempFile, err := os.CreateTemp("", "rtt-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	_, err = tempFile.Write(model)
+	if err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tempFile.Sync()
+
+	b := NewDataBag()
+	res := processFile(b, tempFile.Name(), "file.txt", &Options{})
+
+	expected := "file.txt: UTF-16 LE «1 invalid sequence at offset 14», Unix"
+	if res != expected {
+		t.Errorf("Expected \"%s\", got \"%s\"", expected, res)
+	}
+	if b.FilesTypes.NonText != 0 {
+		t.Errorf("Expected FilesTypes.NonText 0, got %d", b.FilesTypes.NonText)
+	}
+}
+
+func TestUsageMarkup(t *testing.T) {
+	if err := MyMarkup.ValidateTexts(map[string]string{"usage": usageText(), "extended usage": extendedUsageText()}); err != nil {
+		t.Errorf("Invalid markup:\n%v", err)
+	}
+}
@@ -3,6 +3,10 @@
 //
 // 2025-07-05	PV 		First version, translated from Rust by Gemini
 // 2025-07-07 	PV 		Compact options -a+ and -a-
+// 2026-10-19 	PV 		Option -z
+// 2026-10-19 	PV 		Option --color
+// 2026-10-19 	PV 		Hidden option --help-format
+// 2026-10-19 	PV 		decoder configured by options, instead of modifying TextAutoDecode.DefaultDecoder
 
 package main
 
@@ -21,6 +25,7 @@ type Options struct {
 	Sources          []string
 	Autorecurse      bool
 	ShowOnlyWarnings bool
+	Decompress       bool
 	Verbose          bool
 }
 
@@ -32,7 +37,12 @@ func header() {
 func usage() {
 	header()
 	fmt.Println()
-	text := `⌊Usage⌋: {APP_NAME} ¬[⦃?⦄|⦃-?⦄|⦃-h⦄|⦃??⦄|⦃-??⦄] [⦃-a+⦄|⦃-a-⦄] [⦃-w⦄] [⦃-v⦄] [⟨source⟩...]
+	MyMarkup.RenderMarkup(usageText())
+}
+
+// usageText returns usage in MyMarkup
+func usageText() string {
+	text := `⌊Usage⌋: {APP_NAME} ¬[⦃?⦄|⦃-?⦄|⦃-h⦄|⦃??⦄|⦃-??⦄] [⦃-a+⦄|⦃-a-⦄] [⦃-w⦄] [⦃-v⦄] [⦃-z⦄] [⦃--color=⟨mode⟩⦄] [⟨source⟩...]
 
 ⌊Options⌋:
 ⦃?⦄|⦃-?⦄|⦃-h⦄  ¬Show this message
@@ -40,22 +50,21 @@ func usage() {
 ⦃-a+⦄|⦃-a-⦄  ¬Enable (default) or disable glob autorecurse mode (see extended usage)
 ⦃-w⦄       ¬Only show warnings
 ⦃-v⦄       ¬Verbose output
+⦃-z⦄       ¬Analyze decompressed text of gzip, bzip2 and xz compressed files
+⦃--color=⟨mode⟩⦄ ¬Use colors: ⦃auto⦄ (default, when output is a terminal and NO_COLOR is not set), ⦃always⦄ or ⦃never⦄
 ⟨source⟩   ¬File or directory to search, glob syntax supported (see extended usage). Without source, search stdin.
 `
 
-	MyMarkup.RenderMarkup(strings.ReplaceAll(text, "{APP_NAME}", APP_NAME))
+	return strings.ReplaceAll(text, "{APP_NAME}", APP_NAME)
 }
 
 func extendedUsage() {
 	header()
-	fmt.Println("Copyright ©2025 Pierre Violent")
-	fmt.Println()
-	MyMarkup.RenderMarkup("⌊Dependencies⌋:")
-	fmt.Println("MyGlob:", MyGlob.Version())
-	fmt.Println("TextAutoDecode:", TextAutoDecode.Version())
-	fmt.Println("MyMarkup:", MyMarkup.Version())
-	fmt.Println()
-	
+	MyMarkup.RenderMarkup(extendedUsageText())
+}
+
+// extendedUsageText returns copyright, dependencies and advanced usage notes in MyMarkup
+func extendedUsageText() string {
 	text := `⟪⌊Advanced usage notes⌋⟫
 
 Counts include with and without BOM variants.
@@ -75,9 +84,19 @@ Counts include with and without BOM variants.
 - ¬Mixed EOL styles in a file
 - ¬Different EOL styles for a given file type (extension) in a directory`
 
-	MyMarkup.RenderMarkup(strings.ReplaceAll(text, "{APP_NAME}", APP_NAME))
-	fmt.Println()
-	MyMarkup.RenderMarkup(MyGlob.GlobSyntax())
+	return "Copyright ©2025 Pierre Violent\n\n" +
+		"⌊Dependencies⌋:\n" +
+		"MyGlob: " + MyGlob.Version() + "\n" +
+		"TextAutoDecode: " + TextAutoDecode.Version() + "\n" +
+		"MyMarkup: " + MyMarkup.Version() + "\n\n" +
+		strings.ReplaceAll(text, "{APP_NAME}", APP_NAME) + "\n\n" +
+		MyGlob.GlobSyntax()
+}
+
+// helpDocument prints usage and advanced usage notes in format, for hidden option --help-format used to publish
+// documentation
+func helpDocument(format MyMarkup.OutputFormat) {
+	fmt.Print(MyMarkup.BuildDocument(format, APP_NAME, APP_VERSION, APP_DESCRIPTION, usageText()+"\n\n"+extendedUsageText()))
 }
 
 func NewOptions() (*Options, error) {
@@ -97,9 +116,18 @@ func NewOptions() (*Options, error) {
 	autorecurseMinus := flag.Bool("a-", false, "Synonym for -a -")
 	flag.BoolVar(&options.ShowOnlyWarnings, "w", false, "Only show warnings")
 	flag.BoolVar(&options.Verbose, "v", false, "Verbose output")
+	flag.BoolVar(&options.Decompress, "z", false, "Analyze decompressed text of gzip, bzip2 and xz compressed files")
+	flag.Var(&MyMarkup.Color, "color", "Use colors: auto (default), always or never")
+	var helpFormat MyMarkup.OutputFormat
+	flag.Var(&helpFormat, "help-format", "Hidden option, print help in html, md or man format")
 
 	flag.Parse()
 
+	if helpFormat != MyMarkup.OF_Terminal {
+		helpDocument(helpFormat)
+		os.Exit(0)
+	}
+
 	if *showHelp || *showHelp2 || flag.NArg() > 0 && (flag.Args()[0] == "?" || flag.Args()[0] == "help") {
 		usage()
 		os.Exit(0)
@@ -132,4 +160,15 @@ func NewOptions() (*Options, error) {
 	options.Sources = flag.Args()
 
 	return options, nil
-}
\ No newline at end of file
+}
+
+// decoder returns a copy of TextAutoDecode.DefaultDecoder configured according to options, so the package global is
+// never modified
+func (options *Options) decoder() *TextAutoDecode.Decoder {
+	d := TextAutoDecode.DefaultDecoder
+	// Unicode files with a few invalid sequences are reported as such
+	d.InvalidPolicy = TextAutoDecode.IP_Report
+	// With -z, encoding of decompressed text of gzip, bzip2 and xz files is shown, followed by compression format
+	d.Decompress = options.Decompress
+	return &d
+}
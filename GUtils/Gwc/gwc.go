@@ -3,6 +3,18 @@
 //
 // 2025-07-10 	PV 		First version
 // 2025-07-11 	PV 		1.1 Parallel version of ProcessText
+// 2026-10-19 	PV 		1.2.0 Option -u to count hard links and other aliases of a file only once
+// 2026-10-19 	PV 		1.2.1 Use TextAutoDecode.DetectFile to skip binary files without reading them completely
+// 2026-10-19 	PV 		1.2.2 Decode stdin with TextAutoDecode.DecodeBytes, so UTF-16 piped input is supported
+// 2026-10-19 	PV 		1.3.0 Files larger than 1 GB are counted with a streaming decoder instead of being skipped
+// 2026-10-19 	PV 		1.3.1 Lines count from TextAutoDecode line statistics
+// 2026-10-19 	PV 		1.4.0 Option -z to count decompressed text of compressed files
+// 2026-10-19 	PV 		1.4.1 Option --color for usage
+// 2026-10-19 	PV 		1.4.2 Hidden option --help-format to print help in html, md or man format
+// 2026-10-19 	PV 		1.4.3 Words counted in decoded text directly, without normalized copy and slice of lines; fixed words count of texts of more than 6000 lines, always 0
+// 2026-10-19 	PV 		1.4.4 With -z, files are always counted with the streaming decoder, since decompressed size is unknown
+// 2026-10-19 	PV 		1.4.5 Files are read once, without a DetectFile pre-pass, since ReadTextFile stops early on non-text files
+// 2026-10-19 	PV 		1.4.6 Files decoded with a decoder configured by options, TextAutoDecode.DefaultDecoder is not modified
 
 /* Before parallelism, on WOTAN:
 
@@ -22,7 +34,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strings"
 	"time"
 	"unicode/utf8"
 
@@ -32,7 +43,7 @@ import (
 
 const (
 	APP_NAME        = "gwc"
-	APP_VERSION     = "1.1.0"
+	APP_VERSION     = "1.4.6"
 	APP_DESCRIPTION = "Word Count utility in Go"
 )
 
@@ -58,9 +69,10 @@ func main() {
 	start := time.Now()
 
 	bTotal := DataBag{}
+	aliasesSkipped := 0
 
 	for _, source := range options.Sources {
-		gs, err := MyGlob.New(source).Autorecurse(options.Autorecurse).Compile()
+		gs, err := MyGlob.New(source).Autorecurse(options.Autorecurse).UniqueFiles(options.UniqueFiles).Compile()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%s: Error building MyGlob: %v\n", APP_NAME, err)
 			continue
@@ -77,6 +89,7 @@ func main() {
 				processFile(&bTotal, ma.Path, options)
 			}
 		}
+		aliasesSkipped += gs.Stats().AliasesSkipped
 	}
 
 	// If no source has been provided, use stdin
@@ -98,6 +111,9 @@ func main() {
 	}
 
 	if options.Verbose {
+		if aliasesSkipped > 0 {
+			fmt.Printf("\n%d alias(es) of already counted files skipped", aliasesSkipped)
+		}
 		fmt.Printf("\n%d files(s) searched in %.3fs\n", bTotal.files_count, duration.Seconds())
 	}
 }
@@ -119,16 +135,35 @@ func processStdin(options *Options) error {
 	if err != nil {
 		return err
 	}
-	inputString := string(byteData)
+	tadRes := options.decoder().DecodeBytes(byteData)
+	if tadRes.Encoding == TextAutoDecode.TFE_NotText {
+		if options.Verbose {
+			fmt.Printf("%s: ignored non-text stdin\n", APP_NAME)
+		}
+		return nil
+	}
 
 	b := DataBag{}
-	processText(&b, inputString, "(stdin)", options, int64(len(inputString)))
+	processText(&b, tadRes, "(stdin)", options, int64(len(byteData)))
 	return nil
 }
 
 func processFile(b *DataBag, path string, options *Options) {
-	tadRes, err := TextAutoDecode.ReadTextFile(path)
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: Error getting info for file %s: %v\n", APP_NAME, path, err)
+		return
+	}
+	if fileInfo.Size() > 1024*1024*1024 || options.Decompress {
+		// Very large files are not decoded into a single string. With -z, decompressed size is unknown, a small
+		// compressed file can expand to several GB, so files are always streamed. Bytes count is file size, that is
+		// compressed size for a compressed file.
+		processLargeFile(b, path, options, fileInfo.Size())
+		return
+	}
 
+	// Non-text files are rejected after reading only their beginning
+	tadRes, err := options.decoder().ReadTextFile(path)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "*** Error reading file %s: %v\n", path, err)
 		return
@@ -139,61 +174,75 @@ func processFile(b *DataBag, path string, options *Options) {
 			fmt.Printf("%s: ignored non-text file %s\n", APP_NAME, path)
 		}
 	} else {
-		fileInfo, err := os.Stat(path)
+		processText(b, tadRes, path, options, fileInfo.Size())
+	}
+}
+
+// processLargeFile counts lines, words and chars while decoding, with the same rules as processText
+func processLargeFile(b *DataBag, path string, options *Options, filesize int64) {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "*** Error reading file %s: %v\n", path, err)
+		return
+	}
+	defer f.Close()
+
+	rd, _, err := options.decoder().NewDecodingReader(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "*** Error reading file %s: %v\n", path, err)
+		return
+	}
+	if rd == nil {
+		if options.Verbose {
+			fmt.Printf("%s: ignored non-text file %s\n", APP_NAME, path)
+		}
+		return
+	}
+
+	words, chars := 0, 0
+	inWord := false
+	for {
+		r, _, err := rd.ReadRune()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s: Error getting info for file %s: %v\n", APP_NAME, path, err)
+			fmt.Fprintf(os.Stderr, "*** Error reading file %s: %v\n", path, err)
 			return
 		}
-		if fileInfo.Size() > 1024*1024*1024 {
-			if options.Verbose {
-				fmt.Printf("%s: ignored very large file %s, size: %d bytes\n", APP_NAME, path, fileInfo.Size())
+		chars++
+
+		switch r {
+		case '\n', '\r', ' ', '\t':
+			inWord = false
+		default:
+			if !inWord {
+				words++
+				inWord = true
 			}
-		} else {
-			processText(b, tadRes.Text, path, options, fileInfo.Size())
 		}
 	}
-}
-
-func processText(b *DataBag, txt, path string, options *Options, filesize int64) {
-	normalized := strings.ReplaceAll(strings.ReplaceAll(txt, "\r\n", "\n"), "\r", "\n")
-	textLines := strings.Split(normalized, "\n")
-
-	lines := len(textLines)
-	chars := utf8.RuneCountInString(txt)
-	bytes := int(filesize) // sizes longer than 1GB are skipped
+	lines := rd.Lines().LineCount
 
-	// Special correction: If last line ends with \n, strings.Split counts an extra empty line in Go, while it's not
-	// counted in Rust version and also in Linux wc command, hence this manual correction
-	if strings.HasSuffix(normalized, "\n") {
-		lines--
+	if !options.ShowOnlyTotal {
+		printLine(lines, words, chars, int(filesize), path)
 	}
 
-	// To count words, if we have more than 6000 lines, then we use a goroutine to count in blocks of 6000 lines since
-	// empirically that's near the most efficient size
-	SLICESIZE := 6000
+	b.files_count++
+	b.lines_count += lines
+	b.words_count += words
+	b.chars_count += chars
+	b.bytes_count += int(filesize)
+}
 
-	words := 0
-	if lines <= SLICESIZE {
-		words = count_slice_words(textLines)
-	} else {
-		blocks := len(textLines)/SLICESIZE + 1
-		reschan := make(chan int, blocks)
-		sl := 0
-		for i := 0; i < len(textLines); i += SLICESIZE {
-			end := i + SLICESIZE
-			if end > len(textLines) {
-				end = len(textLines)
-			}
-			sl++
-			go count_slice_words_to_reschan(textLines[i:end], reschan)
-		}
+func processText(b *DataBag, tadRes TextAutoDecode.TextAutoDecode, path string, options *Options, filesize int64) {
+	txt := tadRes.Text
 
-		words := 0
-		for i := 0; i < sl; i++ {
-			words += <-reschan
-		}
-		close(reschan)
-	}
+	// A last line ending with EOL is not counted as an extra empty line, but a last line without EOL is counted
+	lines := tadRes.Lines.LineCount
+	chars := utf8.RuneCountInString(txt)
+	bytes := int(filesize) // sizes longer than 1GB are skipped
+	words := count_words(txt)
 
 	if !options.ShowOnlyTotal {
 		printLine(lines, words, chars, bytes, path)
@@ -206,17 +255,51 @@ func processText(b *DataBag, txt, path string, options *Options, filesize int64)
 	b.bytes_count += bytes
 }
 
-func count_slice_words_to_reschan(lines []string, reschan chan<- int) {
-	reschan <- count_slice_words(lines)
+// count_words counts words separated by spaces, tabs and EOLs, with the same rules as processLargeFile.
+// Large texts are split in blocks ending after a separator, counted by goroutines, since empirically blocks of about
+// 6000 lines are near the most efficient size
+func count_words(txt string) int {
+	const BLOCKSIZE = 256 * 1024
+	if len(txt) <= BLOCKSIZE {
+		return count_block_words(txt)
+	}
+
+	reschan := make(chan int)
+	blocks := 0
+	for len(txt) > 0 {
+		end := min(BLOCKSIZE, len(txt))
+		for end < len(txt) && !is_word_separator(txt[end-1]) {
+			end++
+		}
+		go func(block string) {
+			reschan <- count_block_words(block)
+		}(txt[:end])
+		blocks++
+		txt = txt[end:]
+	}
+
+	words := 0
+	for i := 0; i < blocks; i++ {
+		words += <-reschan
+	}
+	return words
 }
 
-func count_slice_words(lines []string) int {
+// count_block_words counts words of txt. Separators are ASCII, so bytes of multi-byte UTF-8 characters never match
+func count_block_words(txt string) int {
 	words := 0
-	for _, line := range lines {
-		splitFunc := func(r rune) bool {
-			return r == ' ' || r == '\t'
+	inWord := false
+	for i := 0; i < len(txt); i++ {
+		if is_word_separator(txt[i]) {
+			inWord = false
+		} else if !inWord {
+			words++
+			inWord = true
 		}
-		words += len(strings.FieldsFunc(strings.Trim(line, " \t"), splitFunc))
 	}
 	return words
 }
+
+func is_word_separator(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
@@ -1,11 +1,23 @@
 // Tests for Gwc
 //
 // 2025-07-10 	PV 		First version
+// 2026-10-19 	PV 		TestCountLarge, streaming count used for files larger than 1 GB
+// 2026-10-19 	PV 		TestCountLarge with a text counted by blocks in parallel
+// 2026-10-19 	PV 		TestUsageMarkup, using MyMarkup.ValidateTexts
+// 2026-10-19 	PV 		TestGzip, compressed files are streamed and bytes count is compressed size
+// 2026-10-19 	PV 		TestGzip no longer modifies TextAutoDecode.DefaultDecoder
 
 package main
 
 import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/PieVio/MyMarkup"
+	"github.com/PieVio/TextAutoDecode"
 )
 
 func assert_eq(t *testing.T, a, b int) {
@@ -18,7 +30,7 @@ func assert_eq(t *testing.T, a, b int) {
 func TestCount1(t *testing.T) {
 	o := Options{ShowOnlyTotal: true }
 	b := DataBag{}
-    processText(&b, "Once upon a time\nWas a King and a Prince\nIn a far, far away kingdom.", "(test)", &o, 68)
+    processText(&b, TextAutoDecode.DecodeBytes([]byte("Once upon a time\nWas a King and a Prince\nIn a far, far away kingdom.")), "(test)", &o, 68)
     assert_eq(t, b.files_count, 1)
     assert_eq(t, b.lines_count, 3)
     assert_eq(t, b.words_count, 16)
@@ -29,7 +41,7 @@ func TestCount1(t *testing.T) {
 func TestCount2(t *testing.T) {
 	o := Options{ShowOnlyTotal: true }
 	b := DataBag{}
-    processText(&b, " Aé♫山𝄞🐗   🐷🐽🐖 ", "(test)", &o, 34)
+    processText(&b, TextAutoDecode.DecodeBytes([]byte(" Aé♫山𝄞🐗   🐷🐽🐖 ")), "(test)", &o, 34)
     assert_eq(t, b.files_count, 1)
     assert_eq(t, b.lines_count, 1)
     assert_eq(t, b.words_count, 2)
@@ -37,6 +49,51 @@ func TestCount2(t *testing.T) {
     assert_eq(t, b.bytes_count, 34)
 }
 
+func TestCountLarge(t *testing.T) {
+	texts := []string{"Once upon a time\nWas a King and a Prince\nIn a far, far away kingdom.", " Aé♫山𝄞🐗   🐷🐽🐖 ", "One\r\ntwo\rthree\n\nfour\n",
+		// Words of large texts are counted by blocks in parallel
+		strings.Repeat("Il était une fois\r\nun roi\tet un prince 山🐗 ", 20000)}
+	for _, text := range texts {
+		path := filepath.Join(t.TempDir(), "large.txt")
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		o := Options{ShowOnlyTotal: true}
+		b1 := DataBag{}
+		processText(&b1, TextAutoDecode.DecodeBytes([]byte(text)), "(test)", &o, int64(len(text)))
+		b2 := DataBag{}
+		processLargeFile(&b2, path, &o, int64(len(text)))
+		if b1 != b2 {
+			t.Errorf("Streaming count of %q: got %+v, want %+v", text, b2, b1)
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	text := "Once upon a time\nWas a King and a Prince\nIn a far, far away kingdom.\n"
+	path := filepath.Join(t.TempDir(), "tale.txt.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	zw.Write([]byte(text))
+	zw.Close()
+	f.Close()
+	fi, _ := os.Stat(path)
+
+	// Option -z
+	o := Options{ShowOnlyTotal: true, Decompress: true}
+	b := DataBag{}
+	processFile(&b, path, &o)
+	assert_eq(t, b.files_count, 1)
+	assert_eq(t, b.lines_count, 3)
+	assert_eq(t, b.words_count, 16)
+	assert_eq(t, b.chars_count, len(text))
+	assert_eq(t, b.bytes_count, int(fi.Size()))
+}
+
 func TestFileAscii(t *testing.T) {
 	o := Options{ShowOnlyTotal: true }
 	b := DataBag{}
@@ -69,3 +126,9 @@ func TestFileUtf16lebom(t *testing.T) {
     assert_eq(t, b.chars_count, 1145)
     assert_eq(t, b.bytes_count, 2292)
 }
+
+func TestUsageMarkup(t *testing.T) {
+	if err := MyMarkup.ValidateTexts(map[string]string{"usage": usageText(), "extended usage": extendedUsageText()}); err != nil {
+		t.Errorf("Invalid markup:\n%v", err)
+	}
+}
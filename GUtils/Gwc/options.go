@@ -2,6 +2,11 @@
 // Parse and validate command line options, returning a clean Options struct
 //
 // 2025-07-10	PV 		First version
+// 2026-10-19	PV 		Option -u
+// 2026-10-19	PV 		Option -z
+// 2026-10-19	PV 		Option --color
+// 2026-10-19	PV 		Hidden option --help-format
+// 2026-10-19	PV 		decoder configured by options, instead of modifying TextAutoDecode.DefaultDecoder
 
 package main
 
@@ -20,6 +25,8 @@ type Options struct {
 	Sources       []string
 	Autorecurse   bool
 	ShowOnlyTotal bool
+	UniqueFiles   bool
+	Decompress    bool
 	Verbose       bool
 }
 
@@ -31,40 +38,55 @@ func header() {
 func usage() {
 	header()
 	fmt.Println()
-	text := `⌊Usage⌋: {APP_NAME} ¬[⦃?⦄|⦃-?⦄|⦃-h⦄|⦃??⦄|⦃-??⦄] [⦃-a+⦄|⦃-a-⦄] [⦃-t⦄] [⦃-v⦄] [⟨source⟩...]
+	MyMarkup.RenderMarkup(usageText())
+}
+
+// usageText returns usage in MyMarkup
+func usageText() string {
+	text := `⌊Usage⌋: {APP_NAME} ¬[⦃?⦄|⦃-?⦄|⦃-h⦄|⦃??⦄|⦃-??⦄] [⦃-a+⦄|⦃-a-⦄] [⦃-t⦄] [⦃-u⦄] [⦃-v⦄] [⦃-z⦄] [⦃--color=⟨mode⟩⦄] [⟨source⟩...]
 
 ⌊Options⌋:
 ⦃?⦄|⦃-?⦄|⦃-h⦄  ¬Show this message
 ⦃??⦄|⦃-??⦄   ¬Show advanced usage notes
 ⦃-a+⦄|⦃-a-⦄  ¬Enable (default) or disable glob autorecurse mode (see extended usage)
 ⦃-t⦄       ¬Only show total line
+⦃-u⦄       ¬Count hard links and other aliases of a file only once
 ⦃-v⦄       ¬Verbose output
+⦃-z⦄       ¬Count decompressed text of gzip, bzip2 and xz compressed files
+⦃--color=⟨mode⟩⦄ ¬Use colors: ⦃auto⦄ (default, when output is a terminal and NO_COLOR is not set), ⦃always⦄ or ⦃never⦄
 ⟨source⟩   ¬File or directory to search, glob syntax supported (see extended usage). Without source, search stdin.`
 
-	MyMarkup.RenderMarkup(strings.ReplaceAll(text, "{APP_NAME}", APP_NAME))
+	return strings.ReplaceAll(text, "{APP_NAME}", APP_NAME)
 }
 
 func extendedUsage() {
 	header()
-	fmt.Println("Copyright ©2025 Pierre Violent")
-	fmt.Println()
-	MyMarkup.RenderMarkup("⌊Dependencies⌋:")
-	fmt.Println("MyGlob:", MyGlob.Version())
-	fmt.Println("TextAutoDecode:", TextAutoDecode.Version())
-	fmt.Println("MyMarkup:", MyMarkup.Version())
-	fmt.Println()
+	MyMarkup.RenderMarkup(extendedUsageText())
+}
 
+// extendedUsageText returns copyright, dependencies and advanced usage notes in MyMarkup
+func extendedUsageText() string {
 	text := `⟪⌊Advanced usage notes⌋⟫
 
-The four numerical fields report lines, words, characters and bytes counts. For UTF-8 or UTF-16 encoded files, a character is a Unicode codepoint, so bytes and characters counts may be different. Characters count neither include line terminators, nor BOM if present. Bytes count is the total file size as reported by the operating system, including line terminators and BOM if present.
+The four numerical fields report lines, words, characters and bytes counts. For UTF-8 or UTF-16 encoded files, a character is a Unicode codepoint, so bytes and characters counts may be different. Characters count neither include line terminators, nor BOM if present. Bytes count is the total file size as reported by the operating system, including line terminators and BOM if present. With option ⦃-z⦄, bytes count of a compressed file is its compressed size.
 
 Words are series of character(s) separated by space(s), spaces are either ASCII 9 (tab) or 32 (regular space).  Unicode "fancy spaces" are not considered.
 
 Lines end with ⟦\r⟧, ⟦\n⟧ or ⟦\r\n⟧. If the last line of the file ends with such termination character, an extra empty line is counted.`
 
-	MyMarkup.RenderMarkup(strings.ReplaceAll(text, "{APP_NAME}", APP_NAME))
-	fmt.Println()
-	MyMarkup.RenderMarkup(MyGlob.GlobSyntax())
+	return "Copyright ©2025 Pierre Violent\n\n" +
+		"⌊Dependencies⌋:\n" +
+		"MyGlob: " + MyGlob.Version() + "\n" +
+		"TextAutoDecode: " + TextAutoDecode.Version() + "\n" +
+		"MyMarkup: " + MyMarkup.Version() + "\n\n" +
+		strings.ReplaceAll(text, "{APP_NAME}", APP_NAME) + "\n\n" +
+		MyGlob.GlobSyntax()
+}
+
+// helpDocument prints usage and advanced usage notes in format, for hidden option --help-format used to publish
+// documentation
+func helpDocument(format MyMarkup.OutputFormat) {
+	fmt.Print(MyMarkup.BuildDocument(format, APP_NAME, APP_VERSION, APP_DESCRIPTION, usageText()+"\n\n"+extendedUsageText()))
 }
 
 func NewOptions() (*Options, error) {
@@ -83,10 +105,20 @@ func NewOptions() (*Options, error) {
 	autorecursePlus := flag.Bool("a+", false, "Synonym for -a +")
 	autorecurseMinus := flag.Bool("a-", false, "Synonym for -a -")
 	flag.BoolVar(&options.ShowOnlyTotal, "t", false, "Only show total line")
+	flag.BoolVar(&options.UniqueFiles, "u", false, "Count hard links and other aliases of a file only once")
 	flag.BoolVar(&options.Verbose, "v", false, "Verbose output")
+	flag.BoolVar(&options.Decompress, "z", false, "Count decompressed text of gzip, bzip2 and xz compressed files")
+	flag.Var(&MyMarkup.Color, "color", "Use colors: auto (default), always or never")
+	var helpFormat MyMarkup.OutputFormat
+	flag.Var(&helpFormat, "help-format", "Hidden option, print help in html, md or man format")
 
 	flag.Parse()
 
+	if helpFormat != MyMarkup.OF_Terminal {
+		helpDocument(helpFormat)
+		os.Exit(0)
+	}
+
 	if *showHelp || *showHelp2 || flag.NArg() > 0 && (flag.Args()[0] == "?" || flag.Args()[0] == "help") {
 		usage()
 		os.Exit(0)
@@ -119,3 +151,12 @@ func NewOptions() (*Options, error) {
 
 	return options, nil
 }
+
+// decoder returns a copy of TextAutoDecode.DefaultDecoder configured according to options, so the package global is
+// never modified
+func (options *Options) decoder() *TextAutoDecode.Decoder {
+	d := TextAutoDecode.DefaultDecoder
+	// With -z, gzip, bzip2 and xz files are decoded after decompression, and counted with the streaming decoder
+	d.Decompress = options.Decompress
+	return &d
+}
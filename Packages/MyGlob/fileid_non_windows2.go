@@ -0,0 +1,26 @@
+//go:build !windows
+
+// fileid_non_windows2.go (2 suffix, since a _windows.go suffix restricts build to Windows)
+// File identity on non-Windows systems: device and inode
+//
+// 2026-10-19	PV 		First version
+
+package MyGlob
+
+import (
+	"os"
+	"syscall"
+)
+
+// getFileId returns the identity of a file, the same for all hard links or bind-mounted copies of a file
+func getFileId(path string) (fileId, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileId{}, err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileId{}, MyGlobError{"No device and inode information for " + path}
+	}
+	return fileId{device: uint64(st.Dev), index: uint64(st.Ino)}, nil
+}
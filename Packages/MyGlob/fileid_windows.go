@@ -0,0 +1,33 @@
+//go:build windows
+
+// fileid_windows.go
+// File identity on Windows: volume serial number and file ID
+//
+// 2026-10-19	PV 		First version
+
+package MyGlob
+
+import (
+	"syscall"
+)
+
+// getFileId returns the identity of a file, the same for all hard links of a file
+func getFileId(path string) (fileId, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return fileId{}, err
+	}
+
+	// FILE_FLAG_BACKUP_SEMANTICS is required to open a directory
+	h, err := syscall.CreateFile(pathPtr, 0, syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE, nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return fileId{}, err
+	}
+	defer syscall.CloseHandle(h)
+
+	var info syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(h, &info); err != nil {
+		return fileId{}, err
+	}
+	return fileId{device: uint64(info.VolumeSerialNumber), index: uint64(info.FileIndexHigh)<<32 | uint64(info.FileIndexLow)}, nil
+}
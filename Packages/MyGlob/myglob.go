@@ -8,20 +8,30 @@
 // 2025-09-07	PV 		1.4.0 MaxDepth; IsConstant removed
 // 2025-09-08	PV 		1.5.0 Replaced stack by a queue for more natural output order
 // 2025-09-13   PV      1.5.1 Check for unclosed brackets in glob expressions such as "C:\[a-z"
+// 2026-10-19   PV      1.6.0 Output path formatting: PathStyle, Separator, RelativeTo and Clean
+// 2026-10-19   PV      1.7.0 Dialect (Windows, POSIX, Native) for parsing, Match and path joining
+// 2026-10-19   PV      1.8.0 UniqueFiles to report hard links and bind-mounted copies only once; Stats
+// 2026-10-19   PV      1.9.0 OneFileSystem to stay on the file system of root (find -xdev)
+// 2026-10-19   PV      1.10.0 All iterator, synchronous exploration without goroutine and channel; Explore is now a wrapper
+// 2026-10-19   PV      1.10.1 UniqueFiles also applies to directories, a directory alias is neither reported nor explored
+// 2026-10-19   PV      1.10.2 Statistics counted per exploration, so concurrent iterations of a search don't race
 
 package MyGlob
 
 import (
 	"container/list"
 	"fmt"
+	"iter"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 )
 
 const (
-	LIB_VERSION = "1.5.1"
+	LIB_VERSION = "1.10.2"
 )
 
 // Segment is an interface for a segment of a glob pattern.
@@ -48,6 +58,78 @@ type FilterSegment struct {
 
 func (f FilterSegment) isSegment() {}
 
+// PathStyle controls how the paths of matches are formatted.
+type PathStyle int
+
+const (
+	PS_AsTyped  PathStyle = iota // Paths start with the root as typed in glob pattern (default)
+	PS_Relative                  // Paths relative to current directory, or to the directory set with RelativeTo
+	PS_Absolute                  // Absolute paths
+)
+
+// Dialect defines the path syntax rules used to parse glob patterns, to match paths and to join paths.
+type Dialect int
+
+const (
+	D_Native  Dialect = iota // Windows rules on Windows, POSIX rules on other systems (default)
+	D_Windows                // Both \ and / are separators, drive letters and UNC paths, case-insensitive, no escape character
+	D_POSIX                  // Only / is a separator, \ escapes next character, case-sensitive constant names
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case D_Native:
+		return "Native"
+	case D_Windows:
+		return "Windows"
+	case D_POSIX:
+		return "POSIX"
+	default:
+		return "D??"
+	}
+}
+
+// resolve returns the actual dialect, D_Windows or D_POSIX, used for D_Native
+func (d Dialect) resolve() Dialect {
+	if d == D_Native {
+		if runtime.GOOS == "windows" {
+			return D_Windows
+		}
+		return D_POSIX
+	}
+	return d
+}
+
+func (d Dialect) isSeparator(c rune) bool {
+	return c == '/' || c == '\\' && d.resolve() == D_Windows
+}
+
+// separator returns the separator used by dialect to join paths
+func (d Dialect) separator() rune {
+	if d.resolve() == D_Windows {
+		return '\\'
+	}
+	return '/'
+}
+
+// unescape removes POSIX escape characters from a constant string
+func (d Dialect) unescape(s string) string {
+	if d.resolve() != D_POSIX || !strings.Contains(s, "\\") {
+		return s
+	}
+	var sb strings.Builder
+	escaped := false
+	for _, c := range s {
+		if c == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
 // MyGlobSearch is the main struct of MyGlob.
 type MyGlobSearch struct {
 	root       string
@@ -56,6 +138,27 @@ type MyGlobSearch struct {
 	maxDepth   int
 	//	isConstant  bool
 	channelSize int
+	pathStyle   PathStyle
+	separator   rune
+	relativeTo  string // Absolute path, only used with PS_Relative
+	clean       bool
+	dialect     Dialect // Always resolved, D_Windows or D_POSIX
+	uniqueFiles bool
+	oneFileSys  bool
+	statsMu     sync.Mutex
+	stats       MyGlobStats // Statistics of last completed exploration, protected by statsMu
+}
+
+// MyGlobStats contains statistics about an exploration.
+type MyGlobStats struct {
+	AliasesSkipped          int // Files and directories skipped by UniqueFiles option since already found with another path
+	OtherFileSystemsSkipped int // Directories not explored by OneFileSystem option since on another file system than root
+}
+
+// fileId identifies a file regardless of its path: (device, inode) on Unix, (volume serial number, file ID) on Windows
+type fileId struct {
+	device uint64
+	index  uint64
 }
 
 // MyGlobBuilder is used to build a MyGlobSearch object.
@@ -65,6 +168,13 @@ type MyGlobBuilder struct {
 	maxDepth    int
 	autoRecurse bool
 	channelSize int
+	pathStyle   PathStyle
+	separator   rune
+	relativeTo  string
+	clean       bool
+	dialect     Dialect
+	uniqueFiles bool
+	oneFileSys  bool
 }
 
 // MyGlobError represents an error returned by MyGlob.
@@ -92,6 +202,7 @@ func GlobSyntax() string {
 - ¬The metacharacters ⟦?⟧, ⟦*⟧, ⟦[⟧, ⟦]⟧ can be matched by escaping them between brackets such as ⟦[\?]⟧ or ⟦[\[]⟧. When a ⟦]⟧ occurs immediately following ⟦[⟧ or ⟦[!⟧ then it is interpreted as being part of, rather than ending the character set, so ⟦]⟧ and NOT ⟦]⟧ can be matched by ⟦[]]⟧ and ⟦[!]]⟧ respectively. The ⟦-⟧ character can be specified inside a character sequence pattern by placing it at the start or the end, e.g. ⟦[abc-]⟧.
 - ¬⟦{choice1,choice2...}⟧  match any of the comma-separated choices between braces. Can be nested, and include ⟦?⟧, ⟦*⟧ and character classes.
 - ¬Character classes ⟦[ ]⟧ accept regexp syntax such as ⟦[\d]⟧ to match a single digit, see https://pkg.go.dev/regexp/syntax for character classes and escape sequences supported.
+- ¬On Windows, both ⟦\⟧ and ⟦/⟧ are path separators. On other systems, only ⟦/⟧ is a path separator, and ⟦\⟧ escapes the next character, so ⟦\*⟧ matches a literal ⟦*⟧.
 
 ⌊Autorecurse glob pattern transformation⌋:
 - ¬⟪Constant pattern⟫ (no filter, no ⟦**⟧) pointing to a directory: ⟦/**/*⟧ is appended at the end to search all files of all subdirectories.
@@ -138,9 +249,63 @@ func (b *MyGlobBuilder) ChannelSize(size int) *MyGlobBuilder {
 	return b
 }
 
+// PathStyle sets how the paths of matches are formatted, default is PS_AsTyped.
+func (b *MyGlobBuilder) PathStyle(style PathStyle) *MyGlobBuilder {
+	b.pathStyle = style
+	return b
+}
+
+// Separator forces the path separator used in the paths of matches, for instance '/' to produce paths for scripts
+// on Windows. 0 means OS separator (default).
+func (b *MyGlobBuilder) Separator(sep rune) *MyGlobBuilder {
+	b.separator = sep
+	return b
+}
+
+// RelativeTo sets the base directory of relative paths, and selects PS_Relative path style.
+// Note that paths relative to a directory other than current directory can't be used directly to access files.
+func (b *MyGlobBuilder) RelativeTo(dir string) *MyGlobBuilder {
+	b.relativeTo = dir
+	b.pathStyle = PS_Relative
+	return b
+}
+
+// Clean sets the clean flag: with PS_AsTyped path style, paths of matches are cleaned (no .\ prefix, no final
+// separator, no duplicate separators). Paths are always clean with PS_Relative and PS_Absolute path styles.
+func (b *MyGlobBuilder) Clean(active bool) *MyGlobBuilder {
+	b.clean = active
+	return b
+}
+
+// Dialect sets the path syntax rules used by glob pattern parsing, Match and path joining, default is D_Native.
+// Exploring a pattern written with a foreign dialect works for relative patterns (drive letters and UNC paths only make
+// sense on Windows); then matches are joined with dialect separator, unless Separator is used.
+func (b *MyGlobBuilder) Dialect(d Dialect) *MyGlobBuilder {
+	b.dialect = d
+	return b
+}
+
+// UniqueFiles sets the unique files flag: files are identified by (device, inode), or (volume serial number, file ID)
+// on Windows, and a file reachable by several paths (hard links, bind-mounted copies) is only reported once, with the
+// first path found in exploration order. Directories are also identified, and a directory already explored with
+// another path is not explored again. Skipped aliases are counted in Stats.
+func (b *MyGlobBuilder) UniqueFiles(active bool) *MyGlobBuilder {
+	b.uniqueFiles = active
+	return b
+}
+
+// OneFileSystem sets the one file system flag: the device ID (volume serial number on Windows) of root is recorded,
+// and directories on another device (mounted file systems, network drives, /proc, ...) are not explored, same as
+// find -xdev. Such directories can still be matched. Skipped directories are counted in Stats.
+func (b *MyGlobBuilder) OneFileSystem(active bool) *MyGlobBuilder {
+	b.oneFileSys = active
+	return b
+}
+
 // getRoot separates a constant root prefix from the rest of a glob pattern.
 // This is a direct translation of the provided Rust function's logic.
-func getRoot(globPattern string) (root, remainder string) {
+// With POSIX dialect, escaped metacharacters are part of the constant prefix, and root is returned unescaped.
+func getRoot(globPattern string, d Dialect) (root, remainder string) {
 	glob := globPattern
 	// Instead of an error, treat an empty pattern as "*", similar to shell command behavior.
 	if glob == "" {
@@ -148,18 +313,34 @@ func getRoot(globPattern string) (root, remainder string) {
 	}
 
 	// Find the end of the constant prefix, which is the position of the first glob metacharacter.
-	specialCharIdx := strings.IndexAny(glob, "*?[{")
+	specialCharIdx := -1
+	isPOSIX := d.resolve() == D_POSIX
+	for i := 0; i < len(glob); i++ {
+		if isPOSIX && glob[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("*?[{", glob[i]) >= 0 {
+			specialCharIdx = i
+			break
+		}
+	}
 
 	// Case 1: The pattern contains no special characters.
 	// The entire string is the root, and there is no remainder.
 	if specialCharIdx == -1 {
-		return glob, ""
+		return d.unescape(glob), ""
 	}
 
 	// Case 2: The pattern contains special characters.
 	// We search for a path separator only within the constant part of the pattern.
 	prefix := glob[:specialCharIdx]
-	lastSeparatorIdx := strings.LastIndexAny(prefix, "/\\")
+	var lastSeparatorIdx int
+	if isPOSIX {
+		lastSeparatorIdx = strings.LastIndexByte(prefix, '/')
+	} else {
+		lastSeparatorIdx = strings.LastIndexAny(prefix, "/\\")
+	}
 
 	if lastSeparatorIdx == -1 {
 		// No path separator was found in the constant prefix.
@@ -170,7 +351,7 @@ func getRoot(globPattern string) (root, remainder string) {
 		// A path separator was found.
 		// The root is everything up to and including that last separator.
 		cutPoint := lastSeparatorIdx + 1
-		root = glob[:cutPoint]
+		root = d.unescape(glob[:cutPoint])
 		remainder = glob[cutPoint:]
 	}
 
@@ -179,12 +360,13 @@ func getRoot(globPattern string) (root, remainder string) {
 
 // Compile builds a new MyGlobSearch from the builder.
 func (b *MyGlobBuilder) Compile() (*MyGlobSearch, error) {
-	root, rem := getRoot(b.globPattern)
+	dialect := b.dialect.resolve()
+	root, rem := getRoot(b.globPattern, dialect)
 
 	var segments []Segment
 	var err error
 	if rem != "" {
-		segments, err = globToSegments(rem)
+		segments, err = globToSegments(rem, dialect)
 		if err != nil {
 			return nil, err
 		}
@@ -192,7 +374,7 @@ func (b *MyGlobBuilder) Compile() (*MyGlobSearch, error) {
 
 	if b.autoRecurse {
 		if len(segments) == 0 {
-			if fi, err := os.Stat(root); err == nil && fi.IsDir() {
+			if fi, err := os.Stat(nativePath(root, dialect)); err == nil && fi.IsDir() {
 				segments = append(segments, RecurseSegment{})
 				re, _ := regexp.Compile("(?i)^.*$")
 				segments = append(segments, FilterSegment{re})
@@ -216,6 +398,24 @@ func (b *MyGlobBuilder) Compile() (*MyGlobSearch, error) {
 		}
 	}
 
+	relativeTo := ""
+	if b.pathStyle == PS_Relative {
+		relativeTo = b.relativeTo
+		if relativeTo == "" {
+			relativeTo = "."
+		}
+		relativeTo, err = filepath.Abs(relativeTo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Matches of a foreign dialect are joined with dialect separator
+	separator := b.separator
+	if separator == 0 && dialect != D_Native.resolve() {
+		separator = dialect.separator()
+	}
+
 	return &MyGlobSearch{
 		root:       root,
 		segments:   segments,
@@ -223,20 +423,81 @@ func (b *MyGlobBuilder) Compile() (*MyGlobSearch, error) {
 		maxDepth:   b.maxDepth,
 		//		isConstant:  len(segments) == 0,
 		channelSize: b.channelSize,
+		pathStyle:   b.pathStyle,
+		separator:   separator,
+		relativeTo:  relativeTo,
+		clean:       b.clean,
+		dialect:     dialect,
+		uniqueFiles: b.uniqueFiles,
+		oneFileSys:  b.oneFileSys,
 	}, nil
 }
 
-func globToSegments(globPattern string) ([]Segment, error) {
+// Stats returns statistics about last completed exploration, that is, once All loop is done or Explore channel is
+// closed. Each exploration counts its own statistics, so a search can be iterated concurrently; Stats then returns the
+// statistics of the exploration that completed last.
+func (gs *MyGlobSearch) Stats() MyGlobStats {
+	gs.statsMu.Lock()
+	defer gs.statsMu.Unlock()
+	return gs.stats
+}
+
+// nativePath converts a path written with dialect d into a path usable to access OS file system.
+func nativePath(path string, d Dialect) string {
+	if d == D_Windows && runtime.GOOS != "windows" {
+		return strings.ReplaceAll(path, "\\", "/")
+	}
+	return path
+}
+
+// formatPath formats the path of a match according to path style and separator.
+// In case of error (for instance, no relative path between two different Windows drives), the absolute path is used.
+func (gs *MyGlobSearch) formatPath(path string) string {
+	switch gs.pathStyle {
+	case PS_Absolute:
+		if ap, err := filepath.Abs(path); err == nil {
+			path = ap
+		}
+	case PS_Relative:
+		if ap, err := filepath.Abs(path); err == nil {
+			path = ap
+			if rp, err := filepath.Rel(gs.relativeTo, ap); err == nil {
+				path = rp
+			}
+		}
+	default:
+		if gs.clean {
+			path = filepath.Clean(path)
+		}
+	}
+
+	if gs.separator != 0 && gs.separator != os.PathSeparator {
+		path = strings.Map(func(r rune) rune {
+			if r < 128 && os.IsPathSeparator(uint8(r)) {
+				return gs.separator
+			}
+			return r
+		}, path)
+	}
+	return path
+}
+
+func globToSegments(globPattern string, d Dialect) ([]Segment, error) {
+	isPOSIX := d.resolve() == D_POSIX
+	if isPOSIX && (len(globPattern)-len(strings.TrimRight(globPattern, "\\")))%2 == 1 {
+		return nil, MyGlobError{"Invalid \\ at the end of glob pattern"}
+	}
+
 	// Make sure that pattern ends with path separator to simplyfy code
-	dirSep := string(os.PathSeparator)
-	if !strings.HasSuffix(globPattern, "/") && !strings.HasSuffix(globPattern, "\\") {
-		// If not, append the OS-specific separator.
-		globPattern += dirSep
+	if last := []rune(globPattern); len(last) == 0 || !d.isSeparator(last[len(last)-1]) {
+		// If not, append the dialect separator.
+		globPattern += string(d.separator())
 	}
 
 	var segments []Segment
 	regexBuffer := ""
 	constantBuffer := ""
+	wildBuffer := "" // Same as constantBuffer, but escaped characters are replaced by _ to ignore them as metacharacters
 	braceDepth := 0
 	inBrackets := false
 	iter := []rune(globPattern)
@@ -246,8 +507,19 @@ func globToSegments(globPattern string) ([]Segment, error) {
 		c := iter[i]
 		i++
 
-		if c != '\\' && c != '/' {
+		// POSIX escape: next character is taken literally
+		if isPOSIX && c == '\\' {
+			ec := string(iter[i])
+			i++
+			constantBuffer += ec
+			wildBuffer += "_"
+			regexBuffer += regexp.QuoteMeta(ec)
+			continue
+		}
+
+		if !d.isSeparator(c) {
 			constantBuffer += string(c)
+			wildBuffer += string(c)
 		}
 
 		switch c {
@@ -275,11 +547,11 @@ func globToSegments(globPattern string) ([]Segment, error) {
 				return nil, MyGlobError{fmt.Sprintf("Invalid %c between { }", c)}
 			}
 
-			if constantBuffer == "**" {
+			if wildBuffer == "**" {
 				segments = append(segments, RecurseSegment{})
-			} else if strings.Contains(constantBuffer, "**") {
+			} else if strings.Contains(wildBuffer, "**") {
 				return nil, MyGlobError{fmt.Sprintf("Glob pattern ** must be alone between %c", c)}
-			} else if strings.ContainsAny(constantBuffer, "*?[{") {
+			} else if strings.ContainsAny(wildBuffer, "*?[{") {
 				if braceDepth > 0 {
 					return nil, MyGlobError{"Unclosed {"}
 				}
@@ -293,6 +565,7 @@ func globToSegments(globPattern string) ([]Segment, error) {
 			}
 			regexBuffer = ""
 			constantBuffer = ""
+			wildBuffer = ""
 		case '[':
 			regexBuffer += "["
 			inBrackets = true
@@ -345,6 +618,108 @@ func globToSegments(globPattern string) ([]Segment, error) {
 	return segments, nil
 }
 
+// Match reports whether path matches glob pattern using dialect rules, without accessing file system. It can be used
+// to filter a list of paths, such as a manifest produced on another OS. Autorecurse transformation, MaxDepth and
+// ignored directories are taken into account the same way as Explore.
+func (gs *MyGlobSearch) Match(path string) bool {
+	rootVolume, rootAbs, rootParts := splitPath(gs.root, gs.dialect)
+	volume, abs, parts := splitPath(path, gs.dialect)
+	if rootAbs != abs || !gs.sameName(rootVolume, volume) || len(parts) < len(rootParts) {
+		return false
+	}
+	for i, rp := range rootParts {
+		if !gs.sameName(rp, parts[i]) {
+			return false
+		}
+	}
+	if len(gs.segments) == 0 {
+		return len(parts) == len(rootParts)
+	}
+	return gs.matchSegments(parts[len(rootParts):], 0)
+}
+
+func (gs *MyGlobSearch) matchSegments(parts []string, depth int) bool {
+	if depth == len(gs.segments) {
+		return len(parts) == 0
+	}
+	if len(parts) == 0 {
+		return false
+	}
+
+	switch s := gs.segments[depth].(type) {
+	case ConstantSegment:
+		return gs.sameName(s.Value, parts[0]) && gs.matchSegments(parts[1:], depth+1)
+
+	case FilterSegment:
+		if depth < len(gs.segments)-1 && gs.isIgnoredDir(parts[0]) {
+			return false
+		}
+		return s.Regexp.MatchString(parts[0]) && gs.matchSegments(parts[1:], depth+1)
+
+	case RecurseSegment:
+		// ** matches 0 to maxDepth directories (no limit if maxDepth is 0)
+		for n := 0; n < len(parts); n++ {
+			if n > 0 && (gs.isIgnoredDir(parts[n-1]) || gs.maxDepth > 0 && n > gs.maxDepth) {
+				return false
+			}
+			if gs.matchSegments(parts[n:], depth+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (gs *MyGlobSearch) isIgnoredDir(name string) bool {
+	nlc := strings.ToLower(name)
+	for _, ignored := range gs.ignoreDirs {
+		if ignored == nlc {
+			return true
+		}
+	}
+	return false
+}
+
+// sameName compares two path components, case-insensitive with Windows dialect
+func (gs *MyGlobSearch) sameName(n1, n2 string) bool {
+	if gs.dialect == D_Windows {
+		return strings.EqualFold(n1, n2)
+	}
+	return n1 == n2
+}
+
+// splitPath splits a path into volume (C: or \\server\share with Windows dialect, empty otherwise), absolute flag (path
+// starts with a separator after volume) and non-empty components, ignoring . components.
+func splitPath(path string, d Dialect) (volume string, abs bool, parts []string) {
+	rest := path
+	if d == D_Windows {
+		if len(rest) >= 2 && rest[1] == ':' {
+			volume, rest = rest[:2], rest[2:]
+		} else if len(rest) >= 2 && d.isSeparator(rune(rest[0])) && d.isSeparator(rune(rest[1])) {
+			// UNC path, volume is \\server\share
+			comps := strings.FieldsFunc(rest, d.isSeparator)
+			if len(comps) >= 2 {
+				volume = `\\` + comps[0] + `\` + comps[1]
+				parts = comps[2:]
+			}
+			abs = true
+			return volume, abs, removeDots(parts)
+		}
+	}
+	abs = len(rest) > 0 && d.isSeparator(rune(rest[0]))
+	return volume, abs, removeDots(strings.FieldsFunc(rest, d.isSeparator))
+}
+
+func removeDots(parts []string) []string {
+	res := parts[:0]
+	for _, p := range parts {
+		if p != "." {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
 // MyGlobMatch represents a match from a glob search.
 type MyGlobMatch struct {
 	Path  string
@@ -359,28 +734,86 @@ type searchPendingDirToExplore struct {
 	recurse_depth int
 }
 
-// Explore returns a channel of matches.
+// Explore returns a channel of matches, filled by a goroutine using All.
+// Channel must be read until it's closed, otherwise the goroutine is leaked.
 func (gs *MyGlobSearch) Explore() <-chan MyGlobMatch {
 	ch := make(chan MyGlobMatch, gs.channelSize)
 	go func() {
 		defer close(ch)
+		for ma, err := range gs.All() {
+			if err != nil {
+				ma.Err = err
+			}
+			ch <- ma
+		}
+	}()
+	return ch
+}
+
+// All returns an iterator over matches. Exploration is synchronous, no goroutine or channel is involved, and it
+// stops as soon as the loop body breaks. For errors, a MyGlobMatch with Err field set is returned along with the error.
+func (gs *MyGlobSearch) All() iter.Seq2[MyGlobMatch, error] {
+	return func(yield func(MyGlobMatch, error) bool) {
+		var stats MyGlobStats
+		defer func() {
+			gs.statsMu.Lock()
+			gs.stats = stats
+			gs.statsMu.Unlock()
+		}()
+		seen := make(map[fileId]bool)
+		// send yields a match, and returns false if iteration has to stop
+		send := func(path string, isDir bool) bool {
+			if gs.uniqueFiles {
+				// If file identity can't be read, file is reported anyway
+				if id, err := getFileId(path); err == nil {
+					if seen[id] {
+						stats.AliasesSkipped++
+						return true
+					}
+					seen[id] = true
+				}
+			}
+			return yield(MyGlobMatch{Path: gs.formatPath(path), IsDir: isDir}, nil)
+		}
+
+		rootDevice, checkDevice := uint64(0), false
+		if gs.oneFileSys {
+			if id, err := getFileId(nativePath(gs.root, gs.dialect)); err == nil {
+				rootDevice, checkDevice = id.device, true
+			}
+		}
+		// sameFileSystem returns false for a directory on another file system than root when OneFileSystem is active
+		sameFileSystem := func(dir string) bool {
+			if checkDevice {
+				if id, err := getFileId(dir); err == nil && id.device != rootDevice {
+					stats.OtherFileSystemsSkipped++
+					return false
+				}
+			}
+			return true
+		}
 
 		if len(gs.segments) == 0 {
-			fi, err := os.Stat(gs.root)
+			root := nativePath(gs.root, gs.dialect)
+			fi, err := os.Stat(root)
 			if err != nil {
-				ch <- MyGlobMatch{Err: err}
+				yield(MyGlobMatch{Err: err}, err)
 				return
 			}
-			if fi.IsDir() {
-				ch <- MyGlobMatch{Path: gs.root, IsDir: true}
-			} else {
-				ch <- MyGlobMatch{Path: gs.root}
-			}
+			send(root, fi.IsDir())
 			return
 		}
 
+		// With UniqueFiles, a directory reached by another path (bind mount) is not explored again in the same state
+		type exploredDir struct {
+			id      fileId
+			depth   int
+			recurse bool
+		}
+		explored := make(map[exploredDir]bool)
+
 		queue := list.New()
-		queue.PushBack(searchPendingDirToExplore{path: gs.root, depth: 0})
+		queue.PushBack(searchPendingDirToExplore{path: nativePath(gs.root, gs.dialect), depth: 0})
 
 		for queue.Len() > 0 {
 			item := queue.Front().Value.(searchPendingDirToExplore)
@@ -391,6 +824,15 @@ func (gs *MyGlobSearch) Explore() <-chan MyGlobMatch {
 			if item.depth >= len(gs.segments) {
 				continue
 			}
+			if gs.uniqueFiles {
+				if id, err := getFileId(item.path); err == nil {
+					key := exploredDir{id, item.depth, item.recurse}
+					if explored[key] {
+						continue
+					}
+					explored[key] = true
+				}
+			}
 
 			segment := gs.segments[item.depth]
 			switch s := segment.(type) {
@@ -399,13 +841,11 @@ func (gs *MyGlobSearch) Explore() <-chan MyGlobMatch {
 				fi, err := os.Stat(newPath)
 				if err == nil {
 					if item.depth == len(gs.segments)-1 {
-						if fi.IsDir() {
-							ch <- MyGlobMatch{Path: newPath, IsDir: true}
-						} else {
-							ch <- MyGlobMatch{Path: newPath}
+						if !send(newPath, fi.IsDir()) {
+							return
 						}
 					} else {
-						if fi.IsDir() {
+						if fi.IsDir() && sameFileSystem(newPath) {
 							queue.PushBack(searchPendingDirToExplore{path: newPath, depth: item.depth + 1})
 						}
 					}
@@ -413,7 +853,9 @@ func (gs *MyGlobSearch) Explore() <-chan MyGlobMatch {
 				if item.recurse && (gs.maxDepth == 0 || item.recurse_depth < gs.maxDepth) {
 					for direntry := range readDirStream(item.path, true) {
 						if direntry.Err != nil {
-							ch <- MyGlobMatch{Err: direntry.Err}
+							if !yield(MyGlobMatch{Err: direntry.Err}, direntry.Err) {
+								return
+							}
 							continue
 						}
 						entry := direntry.Entry
@@ -428,7 +870,7 @@ func (gs *MyGlobSearch) Explore() <-chan MyGlobMatch {
 									break
 								}
 							}
-							if !isIgnored {
+							if !isIgnored && sameFileSystem(p) {
 								queue.PushBack(searchPendingDirToExplore{path: p, depth: item.depth, recurse: true, recurse_depth: item.recurse_depth + 1})
 							}
 						}
@@ -442,7 +884,9 @@ func (gs *MyGlobSearch) Explore() <-chan MyGlobMatch {
 				var dirs []string
 				for direntry := range readDirStream(item.path, false) {
 					if direntry.Err != nil {
-						ch <- MyGlobMatch{Err: direntry.Err}
+						if !yield(MyGlobMatch{Err: direntry.Err}, direntry.Err) {
+							return
+						}
 						continue
 					}
 					entry := direntry.Entry
@@ -458,21 +902,29 @@ func (gs *MyGlobSearch) Explore() <-chan MyGlobMatch {
 							}
 						}
 						if !isIgnored {
-							if s.Regexp.MatchString(fname) {
-								if gs.maxDepth == 0 || item.recurse_depth < gs.maxDepth {
-									newPath := filepath.Join(item.path, fname)
-									if item.depth == len(gs.segments)-1 {
-										ch <- MyGlobMatch{Path: newPath, IsDir: true}
-									} else {
-										queue.PushBack(searchPendingDirToExplore{path: newPath, depth: item.depth + 1})
+							newPath := filepath.Join(item.path, fname)
+							isMatch := s.Regexp.MatchString(fname) && (gs.maxDepth == 0 || item.recurse_depth < gs.maxDepth)
+							isLast := item.depth == len(gs.segments)-1
+							// Only check file system if directory has to be explored
+							onRootFS := (isMatch && !isLast || item.recurse) && sameFileSystem(newPath)
+							if isMatch {
+								if isLast {
+									if !send(newPath, true) {
+										return
 									}
+								} else if onRootFS {
+									queue.PushBack(searchPendingDirToExplore{path: newPath, depth: item.depth + 1})
 								}
 							}
-							dirs = append(dirs, filepath.Join(item.path, fname))
+							if onRootFS {
+								dirs = append(dirs, newPath)
+							}
 						}
 					} else {  // File
 						if item.depth == len(gs.segments)-1 && s.Regexp.MatchString(fname) {
-							ch <- MyGlobMatch{Path: filepath.Join(item.path, fname)}
+							if !send(filepath.Join(item.path, fname), false) {
+								return
+							}
 						}
 					}
 				}
@@ -483,11 +935,9 @@ func (gs *MyGlobSearch) Explore() <-chan MyGlobMatch {
 				}
 			}
 		}
-	}()
-	return ch
+	}
 }
 
-
 // func (gs *MyGlobSearch) IsConstant() bool {
 // 	return gs.isConstant
 // }
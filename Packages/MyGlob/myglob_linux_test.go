@@ -0,0 +1,104 @@
+// myglob_linux_test.go
+// Tests for MyGlob package using Linux bind mounts
+//
+// 2026-10-19   PV      Added UniqueFiles test for bind-mounted directories
+// 2026-10-19   PV      TestOneFileSystemMount, OneFileSystem doesn't explore a mounted file system
+
+package MyGlob
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+)
+
+func TestUniqueDirs(t *testing.T) {
+	tmp := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(tmp, "a", "sub"), 0755)
+	_ = os.MkdirAll(filepath.Join(tmp, "b"), 0755)
+	_ = os.WriteFile(filepath.Join(tmp, "a", "sub", "file.txt"), []byte("Hello"), 0644)
+	if err := syscall.Mount(filepath.Join(tmp, "a"), filepath.Join(tmp, "b"), "", syscall.MS_BIND, ""); err != nil {
+		t.Skipf("Bind mount not permitted: %v", err)
+	}
+	defer syscall.Unmount(filepath.Join(tmp, "b"), 0)
+
+	// explore returns directories, as gfind -d, or files
+	explore := func(glob string, unique, dirs bool) ([]string, MyGlobStats) {
+		gs, err := New(glob).UniqueFiles(unique).Compile()
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+		var paths []string
+		for ma, err := range gs.All() {
+			if err != nil {
+				t.Errorf("All error: %v", err)
+				continue
+			}
+			if ma.IsDir == dirs {
+				paths = append(paths, ma.Path)
+			}
+		}
+		return paths, gs.Stats()
+	}
+
+	// The copy found first in traversal order is kept
+	all, _ := explore(filepath.Join(tmp, "**", "*"), false, true)
+	first := filepath.Join(tmp, "a")
+	if slices.Index(all, filepath.Join(tmp, "b")) < slices.Index(all, first) {
+		first = filepath.Join(tmp, "b")
+	}
+
+	paths, stats := explore(filepath.Join(tmp, "**", "*"), true, true)
+	expected := []string{first, filepath.Join(first, "sub")}
+	if !slices.Equal(paths, expected) || stats.AliasesSkipped != 1 {
+		t.Errorf("UniqueFiles on bind-mounted directory: got %v and %d aliases skipped, want %v and 1", paths, stats.AliasesSkipped, expected)
+	}
+
+	// Files of the other copy are not explored
+	paths, _ = explore(filepath.Join(tmp, "**", "*.txt"), true, false)
+	if expected := []string{filepath.Join(first, "sub", "file.txt")}; !slices.Equal(paths, expected) {
+		t.Errorf("UniqueFiles on bind-mounted directory: got %v, want %v", paths, expected)
+	}
+}
+
+func TestOneFileSystemMount(t *testing.T) {
+	// Find a mount point whose parent is on another file system, and an entry it contains
+	var parent, mount, entry string
+	for _, dir := range []string{"/proc", "/sys", "/dev/shm", "/run"} {
+		id, err1 := getFileId(dir)
+		parentId, err2 := getFileId(filepath.Dir(dir))
+		if err1 != nil || err2 != nil || id.device == parentId.device {
+			continue
+		}
+		if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+			parent, mount, entry = filepath.Dir(dir), dir, entries[0].Name()
+			break
+		}
+	}
+	if mount == "" {
+		t.Skip("No mount boundary found")
+	}
+
+	glob := filepath.Join(parent, "*", entry)
+	target := filepath.Join(mount, entry)
+	for _, xdev := range []bool{false, true} {
+		gs, err := New(glob).OneFileSystem(xdev).Compile()
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+		var paths []string
+		for ma, err := range gs.All() {
+			if err == nil {
+				paths = append(paths, ma.Path)
+			}
+		}
+		if found := slices.Contains(paths, target); found == xdev {
+			t.Errorf("OneFileSystem(%v) %s: %s found=%v, want %v", xdev, glob, target, found, !xdev)
+		}
+		if skipped := gs.Stats().OtherFileSystemsSkipped; (skipped > 0) != xdev {
+			t.Errorf("OneFileSystem(%v) %s: got %d dirs skipped", xdev, glob, skipped)
+		}
+	}
+}
@@ -5,12 +5,22 @@
 // 2025-07-13   PV      Tests with chinese characters
 // 2025-08-11   PV      Added getRoot tests
 // 2025-09-07   PV      Added MaxDepth tests
+// 2026-10-19   PV      Added path formatting tests
+// 2026-10-19   PV      Added dialects tests, getRoot tests use Windows dialect explicitly
+// 2026-10-19   PV      Added UniqueFiles test
+// 2026-10-19   PV      Added All test, benchmarks of Explore vs All
+// 2026-10-19   PV      Added concurrent iterations stats test
 
 package MyGlob
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -42,7 +52,7 @@ func TestRegexpConversions(t *testing.T) {
 	})
 
 	t.Run("** pattern", func(t *testing.T) {
-		_, err := globToSegments("**.d" + string(os.PathSeparator))
+		_, err := globToSegments("**.d"+string(os.PathSeparator), D_Native)
 		if err == nil {
 			t.Errorf("Expected error for **.d, got nil")
 		}
@@ -81,7 +91,7 @@ func TestRegexpConversions(t *testing.T) {
 }
 
 func globOneSegmentTest(t *testing.T, globPattern, testString string, isMatch bool) {
-	segments, err := globToSegments(globPattern + string(os.PathSeparator))
+	segments, err := globToSegments(globPattern+string(os.PathSeparator), D_Native)
 	if err != nil {
 		t.Errorf("globToSegments failed for %s: %v", globPattern, err)
 		return
@@ -216,21 +226,21 @@ func TestSearch(t *testing.T) {
 
 func TestSearchErrors(t *testing.T) {
 	t.Run("InvalidGlob", func(t *testing.T) {
-			_, err := New(`C:\**z\\z`).Compile()
+			_, err := New(`C:\**z\\z`).Dialect(D_Windows).Compile()
 			if err == nil {
 				t.Error("Expected error for invalid glob, got nil")
 			}
 		})
 
 	t.Run("InvalidRegex", func(t *testing.T) {
-			_, err := New(`C:\[\d&&\p{ascii]`).Compile()
+			_, err := New(`C:\[\d&&\p{ascii]`).Dialect(D_Windows).Compile()
 			if err == nil {
 				t.Error("Expected error for invalid regex, got nil")
 			}
 		})
 
 	t.Run("UnclosedBracket", func(t *testing.T) {
-		_, err := New(`C:\[Hello`).Compile()
+		_, err := New(`C:\[Hello`).Dialect(D_Windows).Compile()
 		if err == nil {
 			t.Error("Expected error for invalid regex, got nil")
 		}
@@ -264,11 +274,403 @@ func TestGetRoot(t *testing.T) {
 }
 
 func tgr(t *testing.T, pat, root, rem string) {
-	r, s := getRoot(pat)	
+	r, s := getRoot(pat, D_Windows)
 	if r != root {
 		t.Errorf("Pattern %s: Expected root %s, got %s", pat, root, r)
 	}
 	if s != rem {
 		t.Errorf("Pattern: %s, Expected remainder %s, got %s", pat, rem, s)
 	}
-}
\ No newline at end of file
+}
+
+// -----------------------------------------------------------------------------
+// Tests for path formatting
+
+func TestPathStyle(t *testing.T) {
+	tmp := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(tmp, "fruits", "rouges"), 0755)
+	_ = os.WriteFile(filepath.Join(tmp, "fruits", "pomme.txt"), []byte("Pomme"), 0644)
+	_ = os.WriteFile(filepath.Join(tmp, "fruits", "rouges", "fraise.txt"), []byte("Fraise"), 0644)
+	t.Chdir(tmp)
+
+	sep := string(os.PathSeparator)
+	tests := []struct {
+		name     string
+		builder  *MyGlobBuilder
+		expected []string
+	}{
+		{"AsTyped", New("." + sep + "fruits" + sep + "**" + sep + "*.txt"), []string{"fruits" + sep + "pomme.txt", "fruits" + sep + "rouges" + sep + "fraise.txt"}},
+		{"AsTypedConstant", New("." + sep + "fruits" + sep + "pomme.txt"), []string{"." + sep + "fruits" + sep + "pomme.txt"}},
+		{"AsTypedClean", New("." + sep + "fruits" + sep + "pomme.txt").Clean(true), []string{"fruits" + sep + "pomme.txt"}},
+		{"Relative", New(filepath.Join(tmp, "fruits", "**", "*.txt")).PathStyle(PS_Relative), []string{"fruits" + sep + "pomme.txt", "fruits" + sep + "rouges" + sep + "fraise.txt"}},
+		{"RelativeTo", New("fruits" + sep + "**" + sep + "*.txt").RelativeTo(filepath.Join(tmp, "fruits")), []string{"pomme.txt", "rouges" + sep + "fraise.txt"}},
+		{"RelativeToSlash", New("fruits" + sep + "**" + sep + "*.txt").RelativeTo("fruits").Separator('/'), []string{"pomme.txt", "rouges/fraise.txt"}},
+		{"Absolute", New("fruits" + sep + "*.txt").PathStyle(PS_Absolute), []string{filepath.Join(tmp, "fruits", "pomme.txt")}},
+		{"AbsoluteSlash", New("fruits" + sep + "*.txt").PathStyle(PS_Absolute).Separator('/'), []string{filepath.ToSlash(filepath.Join(tmp, "fruits", "pomme.txt"))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs, err := tt.builder.Compile()
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+
+			var paths []string
+			for m := range gs.Explore() {
+				if m.Err != nil {
+					t.Errorf("Explore error: %v", m.Err)
+					continue
+				}
+				paths = append(paths, m.Path)
+			}
+			sort.Strings(paths)
+
+			if strings.Join(paths, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("got %v, want %v", paths, tt.expected)
+			}
+		})
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Tests for dialects, independent of current OS
+
+func TestGetRootPOSIX(t *testing.T) {
+	tgrd(t, D_POSIX, "", ".", "*")
+	tgrd(t, D_POSIX, "/", "/", "")
+	tgrd(t, D_POSIX, "/usr/lib/*.so", "/usr/lib/", "*.so")
+	tgrd(t, D_POSIX, "/usr/**/*.so", "/usr/", "**/*.so")
+	tgrd(t, D_POSIX, `path\*.jpg`, "path*.jpg", "")
+	tgrd(t, D_POSIX, `what\?/*.txt`, "what?/", "*.txt")
+	tgrd(t, D_POSIX, `a\*b/c\[1\]`, "a*b/c[1]", "")
+	tgrd(t, D_POSIX, `dir\\name/*`, `dir\name/`, "*")
+	tgrd(t, D_Windows, `path\*.jpg`, `path\`, "*.jpg")
+	tgrd(t, D_Windows, `C:/path/*.jpg`, "C:/path/", "*.jpg")
+}
+
+func tgrd(t *testing.T, d Dialect, pat, root, rem string) {
+	r, s := getRoot(pat, d)
+	if r != root {
+		t.Errorf("%s pattern %s: Expected root %s, got %s", d, pat, root, r)
+	}
+	if s != rem {
+		t.Errorf("%s pattern: %s, Expected remainder %s, got %s", d, pat, rem, s)
+	}
+}
+
+func TestSegmentsDialects(t *testing.T) {
+	// With POSIX dialect, \ escapes metacharacters
+	segments, err := globToSegments(`file\*.txt`, D_POSIX)
+	if err != nil || len(segments) != 1 {
+		t.Fatalf("globToSegments failed: %v", err)
+	}
+	if cs, ok := segments[0].(ConstantSegment); !ok || cs.Value != "file*.txt" {
+		t.Errorf("Expected ConstantSegment file*.txt, got %#v", segments[0])
+	}
+
+	segments, err = globToSegments(`\**/a\?*`, D_POSIX)
+	if err != nil || len(segments) != 2 {
+		t.Fatalf("globToSegments failed: %v", err)
+	}
+	if fs, ok := segments[0].(FilterSegment); !ok || !fs.Regexp.MatchString("*abc") || fs.Regexp.MatchString("abc") {
+		t.Errorf("Expected FilterSegment matching *abc, got %#v", segments[0])
+	}
+	if fs, ok := segments[1].(FilterSegment); !ok || !fs.Regexp.MatchString("a?bc") || fs.Regexp.MatchString("abbc") {
+		t.Errorf("Expected FilterSegment matching a?bc, got %#v", segments[1])
+	}
+
+	if _, err = globToSegments(`file\`, D_POSIX); err == nil {
+		t.Error("Expected error for final \\, got nil")
+	}
+
+	// With Windows dialect, \ is a separator
+	segments, err = globToSegments(`file\*.txt`, D_Windows)
+	if err != nil || len(segments) != 2 {
+		t.Fatalf("globToSegments failed: %v", err)
+	}
+	if _, ok := segments[1].(FilterSegment); !ok {
+		t.Errorf("Expected FilterSegment, got %#v", segments[1])
+	}
+}
+
+func TestMatchDialects(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		glob    string
+		path    string
+		isMatch bool
+	}{
+		{D_Windows, `C:\Users\*\Documents\**\*.docx`, `c:\users\pierre\documents\work\2025\report.DOCX`, true},
+		{D_Windows, `C:\Users\*\Documents\**\*.docx`, `C:/Users/pierre/Documents/report.docx`, true},
+		{D_Windows, `C:\Users\*\Documents\**\*.docx`, `D:\Users\pierre\Documents\report.docx`, false},
+		{D_Windows, `C:\Users\*\Documents\**\*.docx`, `C:\Users\Documents\report.docx`, false},
+		{D_Windows, `\\server\share\**\*.txt`, `\\SERVER\Share\a\b\c.txt`, true},
+		{D_Windows, `\\server\share\**\*.txt`, `\\server\other\c.txt`, false},
+		{D_Windows, `C:\Windows\notepad.exe`, `c:\windows\NOTEPAD.EXE`, true},
+		{D_Windows, `src\*.go`, `src\main.go`, true},
+		{D_Windows, `src\*.go`, `.\src\main.go`, true},
+		{D_Windows, `src\*.go`, `src\sub\main.go`, false},
+		{D_Windows, `**\*.txt`, `a\.git\b.txt`, false}, // .git is ignored by default
+		{D_POSIX, `/home/*/src/**/*.go`, `/home/pv/src/a/b/c.go`, true},
+		{D_POSIX, `/home/*/src/**/*.go`, `home/pv/src/c.go`, false},
+		{D_POSIX, `/home/*/src/**/*.go`, `/Home/pv/src/c.go`, false},
+		{D_POSIX, `data/file\*.txt`, `data/file*.txt`, true},
+		{D_POSIX, `data/file\*.txt`, `data/fileA.txt`, false},
+		{D_POSIX, `data\\file*.txt`, `data\fileA.txt`, true},
+		{D_POSIX, `data\\file*.txt`, `data/fileA.txt`, false},
+	}
+
+	for _, tt := range tests {
+		gs, err := New(tt.glob).Dialect(tt.dialect).Compile()
+		if err != nil {
+			t.Errorf("%s %s: Compile failed: %v", tt.dialect, tt.glob, err)
+			continue
+		}
+		if gs.Match(tt.path) != tt.isMatch {
+			t.Errorf("%s %s: Match(%s) expected %v", tt.dialect, tt.glob, tt.path, tt.isMatch)
+		}
+	}
+
+	gs, _ := New(`root\**\*.txt`).Dialect(D_Windows).MaxDepth(1).Compile()
+	if !gs.Match(`root\a\b.txt`) || gs.Match(`root\a\b\c.txt`) {
+		t.Error("MaxDepth not respected by Match")
+	}
+}
+
+func TestExploreForeignDialect(t *testing.T) {
+	tmp := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(tmp, "sub"), 0755)
+	_ = os.WriteFile(filepath.Join(tmp, "sub", "a.txt"), []byte("A"), 0644)
+	t.Chdir(tmp)
+
+	for _, d := range []Dialect{D_Windows, D_POSIX} {
+		sep := string(d.separator())
+		gs, err := New("sub" + sep + "*.txt").Dialect(d).Compile()
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+		var paths []string
+		for m := range gs.Explore() {
+			if m.Err != nil {
+				t.Errorf("%s: Explore error: %v", d, m.Err)
+				continue
+			}
+			paths = append(paths, m.Path)
+		}
+		if len(paths) != 1 || paths[0] != "sub"+sep+"a.txt" {
+			t.Errorf("%s: got %v, want [sub%sa.txt]", d, paths, sep)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Tests for UniqueFiles
+
+func TestUniqueFiles(t *testing.T) {
+	tmp := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(tmp, "a"), 0755)
+	_ = os.MkdirAll(filepath.Join(tmp, "b"), 0755)
+	_ = os.WriteFile(filepath.Join(tmp, "a", "file.txt"), []byte("Hello"), 0644)
+	_ = os.WriteFile(filepath.Join(tmp, "b", "other.txt"), []byte("World"), 0644)
+	if err := os.Link(filepath.Join(tmp, "a", "file.txt"), filepath.Join(tmp, "b", "link.txt")); err != nil {
+		t.Skipf("Hard links not supported: %v", err)
+	}
+
+	var firstPath, aliasPath string // Paths of hard-linked file in traversal order, found without UniqueFiles
+	for _, unique := range []bool{false, true} {
+		gs, err := New(filepath.Join(tmp, "**", "*.txt")).UniqueFiles(unique).Compile()
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+		var paths []string
+		for m := range gs.Explore() {
+			if m.Err != nil {
+				t.Errorf("Explore error: %v", m.Err)
+				continue
+			}
+			paths = append(paths, m.Path)
+		}
+
+		expectedFiles, expectedSkipped := 3, 0
+		if unique {
+			expectedFiles, expectedSkipped = 2, 1
+		}
+		if len(paths) != expectedFiles || gs.Stats().AliasesSkipped != expectedSkipped {
+			t.Errorf("UniqueFiles(%v): got %d files and %d aliases skipped, want %d and %d", unique, len(paths), gs.Stats().AliasesSkipped, expectedFiles, expectedSkipped)
+		}
+		if unique && !slices.Contains(paths, filepath.Join(tmp, "b", "other.txt")) {
+			t.Errorf("UniqueFiles: other.txt is missing, got %v", paths)
+		}
+
+		// The first path in traversal order is kept
+		first, alias := filepath.Join(tmp, "a", "file.txt"), filepath.Join(tmp, "b", "link.txt")
+		if !unique {
+			if slices.Index(paths, alias) < slices.Index(paths, first) {
+				first, alias = alias, first
+			}
+			firstPath, aliasPath = first, alias
+		} else if !slices.Contains(paths, firstPath) || slices.Contains(paths, aliasPath) {
+			t.Errorf("UniqueFiles: got %v, want %s kept and %s skipped", paths, firstPath, aliasPath)
+		}
+	}
+}
+
+func TestOneFileSystem(t *testing.T) {
+	tmp := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(tmp, "a", "b"), 0755)
+	_ = os.WriteFile(filepath.Join(tmp, "a", "file.txt"), []byte("Hello"), 0644)
+	_ = os.WriteFile(filepath.Join(tmp, "a", "b", "other.txt"), []byte("World"), 0644)
+
+	// A temporary directory is on a single file system, so results must be the same
+	for _, glob := range []string{filepath.Join(tmp, "**", "*.txt"), filepath.Join(tmp, "a", "b", "*.txt"), filepath.Join(tmp, "*", "*")} {
+		var counts [2]int
+		for i, xdev := range []bool{false, true} {
+			gs, err := New(glob).OneFileSystem(xdev).Compile()
+			if err != nil {
+				t.Fatalf("Compile failed: %v", err)
+			}
+			for m := range gs.Explore() {
+				if m.Err == nil {
+					counts[i]++
+				}
+			}
+			if gs.Stats().OtherFileSystemsSkipped != 0 {
+				t.Errorf("OneFileSystem(%v) %s: got %d dirs skipped, want 0", xdev, glob, gs.Stats().OtherFileSystemsSkipped)
+			}
+		}
+		if counts[0] != counts[1] {
+			t.Errorf("OneFileSystem %s: got %d matches, want %d", glob, counts[1], counts[0])
+		}
+	}
+}
+
+func TestStatsConcurrent(t *testing.T) {
+	tmp := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(tmp, "a"), 0755)
+	_ = os.MkdirAll(filepath.Join(tmp, "b"), 0755)
+	_ = os.WriteFile(filepath.Join(tmp, "a", "file.txt"), []byte("Hello"), 0644)
+	if err := os.Link(filepath.Join(tmp, "a", "file.txt"), filepath.Join(tmp, "b", "link.txt")); err != nil {
+		t.Skipf("Hard links not supported: %v", err)
+	}
+
+	gs, err := New(filepath.Join(tmp, "**", "*.txt")).UniqueFiles(true).Compile()
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	// Each iteration counts its own statistics, Stats returns those of the last completed one
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range gs.All() {
+			}
+		}()
+	}
+	wg.Wait()
+	if skipped := gs.Stats().AliasesSkipped; skipped != 1 {
+		t.Errorf("Concurrent iterations: got %d aliases skipped, want 1", skipped)
+	}
+}
+
+// makeTree creates a tree of dirs×dirs directories containing files files each under root
+func makeTree(tb testing.TB, root string, dirs, files int) {
+	for i := range dirs {
+		for j := range dirs {
+			dir := filepath.Join(root, fmt.Sprintf("d%d", i), fmt.Sprintf("e%d", j))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				tb.Fatal(err)
+			}
+			for k := range files {
+				if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.txt", k)), nil, 0644); err != nil {
+					tb.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+func TestAll(t *testing.T) {
+	tmp := t.TempDir()
+	makeTree(t, tmp, 3, 4)
+
+	gs, err := New(filepath.Join(tmp, "**", "*.txt")).Compile()
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	var fromAll, fromExplore []string
+	for ma, err := range gs.All() {
+		if err != nil {
+			t.Errorf("All error: %v", err)
+			continue
+		}
+		fromAll = append(fromAll, ma.Path)
+	}
+	for ma := range gs.Explore() {
+		if ma.Err == nil {
+			fromExplore = append(fromExplore, ma.Path)
+		}
+	}
+	sort.Strings(fromAll)
+	sort.Strings(fromExplore)
+	if len(fromAll) != 36 || !slices.Equal(fromAll, fromExplore) {
+		t.Errorf("All: got %d matches, Explore: got %d matches, want 36 for both", len(fromAll), len(fromExplore))
+	}
+
+	// Iteration stops when loop body breaks
+	count := 0
+	for range gs.All() {
+		count++
+		if count == 5 {
+			break
+		}
+	}
+	if count != 5 {
+		t.Errorf("All with break: got %d iterations, want 5", count)
+	}
+
+	// Errors are returned both as error and in Err field
+	gs, _ = New(filepath.Join(tmp, "nonexistent")).Compile()
+	for ma, err := range gs.All() {
+		if err == nil || ma.Err != err {
+			t.Errorf("All on nonexistent file: got (%v, %v), want same error twice", ma.Err, err)
+		}
+	}
+}
+
+func benchmarkTree(b *testing.B) string {
+	tmp := b.TempDir()
+	makeTree(b, tmp, 10, 20)
+	return filepath.Join(tmp, "**", "*.txt")
+}
+
+func BenchmarkExplore(b *testing.B) {
+	glob := benchmarkTree(b)
+	for _, size := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("ChannelSize%d", size), func(b *testing.B) {
+			gs, err := New(glob).ChannelSize(size).Compile()
+			if err != nil {
+				b.Fatal(err)
+			}
+			for b.Loop() {
+				for range gs.Explore() {
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAll(b *testing.B) {
+	glob := benchmarkTree(b)
+	gs, err := New(glob).Compile()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for b.Loop() {
+		for range gs.All() {
+		}
+	}
+}
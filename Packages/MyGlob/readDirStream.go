@@ -2,11 +2,15 @@
 // A faster, better, using less memory version of os.ReadDir...
 //
 // 2025-07-13 	PV 		First version from Gemini
+// 2026-10-19 	PV 		Returns an iterator instead of a channel filled by a goroutine, so iteration can stop early without leaking
 
 package MyGlob
 
 import (
+	"errors"
+	"io"
 	"io/fs"
+	"iter"
 	"os"
 )
 
@@ -16,19 +20,13 @@ type DirEntry struct {
 	Err   error
 }
 
-// readDirStream reads directory entries in a separate goroutine and sends them to a channel.
-func readDirStream(dirName string, dirOnly bool) <-chan DirEntry {
-	// Create a channel to return the directory entries.
-	// The buffer size can be tuned for performance.
-	entries := make(chan DirEntry, 500)
-
-	go func() {
-		// Ensure the channel is closed when the goroutine finishes.
-		defer close(entries)
-
+// readDirStream returns an iterator over directory entries, read by batches.
+// Directory is closed when iteration is complete or when the loop body breaks.
+func readDirStream(dirName string, dirOnly bool) iter.Seq[DirEntry] {
+	return func(yield func(DirEntry) bool) {
 		dir, err := os.Open(dirName)
 		if err != nil {
-			entries <- DirEntry{Err: err}
+			yield(DirEntry{Err: err})
 			return
 		}
 		defer dir.Close()
@@ -40,20 +38,19 @@ func readDirStream(dirName string, dirOnly bool) <-chan DirEntry {
 			subEntries, err := dir.ReadDir(100)
 			for _, entry := range subEntries {
 				if !dirOnly || entry.IsDir() {
-					entries <- DirEntry{Entry: entry}
+					if !yield(DirEntry{Entry: entry}) {
+						return
+					}
 				}
 			}
 
 			// io.EOF signals that we've reached the end of the directory.
 			if err != nil {
-				if err.Error() != "EOF" {
-					entries <- DirEntry{Err: err}
+				if !errors.Is(err, io.EOF) {
+					yield(DirEntry{Err: err})
 				}
 				return
 			}
 		}
-	}()
-
-	return entries
+	}
 }
-
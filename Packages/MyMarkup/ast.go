@@ -0,0 +1,316 @@
+// ast.go
+// Parsing of MyMarkup into a tree of nodes, used by all renderers. Parse reports unbalanced brackets with their
+// position, and always returns a usable tree: unclosed styles end with the markup, and stray closing brackets are
+// ignored, so a help text error never leaves the terminal bold or colored.
+//
+// 2026-10-19	PV 		First version
+// 2026-10-19	PV 		Characters of flattened tree are grapheme clusters with a display width
+// 2026-10-19	PV 		tokens is an iterator rather than a slice
+// 2026-10-19	PV 		ValidateTexts
+
+package MyMarkup
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// NodeKind is the type of a Node
+type NodeKind int
+
+const (
+	NK_Root    NodeKind = iota // Whole markup, Children
+	NK_Text                    // Text without markup characters and newlines
+	NK_Style                   // Style applied to Children, between opening and closing brackets
+	NK_Margin                  // ¬ left margin marker
+	NK_Newline                 // End of line
+)
+
+func (nk NodeKind) String() string {
+	switch nk {
+	case NK_Root:
+		return "Root"
+	case NK_Text:
+		return "Text"
+	case NK_Style:
+		return "Style"
+	case NK_Margin:
+		return "Margin"
+	case NK_Newline:
+		return "Newline"
+	}
+	return fmt.Sprintf("NodeKind(%d)", int(nk))
+}
+
+// Style is a set of text styles
+type Style uint8
+
+const (
+	ST_Bold      Style = 1 << iota // ⟪Bold⟫
+	ST_Italic                      // ⟨Italic⟩
+	ST_Underline                   // ⌊Underline⌋
+	ST_Color1                      // ⟦Color1⟧, cyan
+	ST_Color2                      // ⦃Color2⦄, yellow
+)
+
+// Brackets of each style
+var styleBrackets = []struct {
+	style       Style
+	open, close rune
+}{
+	{ST_Bold, '⟪', '⟫'},
+	{ST_Italic, '⟨', '⟩'},
+	{ST_Underline, '⌊', '⌋'},
+	{ST_Color1, '⟦', '⟧'},
+	{ST_Color2, '⦃', '⦄'},
+}
+
+// Position is a location in markup, Line and Column start at 1, Column counts characters (runes)
+type Position struct {
+	Offset int // Byte offset
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+}
+
+// Node is an element of markup tree
+type Node struct {
+	Kind     NodeKind
+	Text     string   // NK_Text
+	Style    Style    // NK_Style, a single style
+	Pos      Position // Position of first character, opening bracket for NK_Style
+	Children []*Node  // NK_Root and NK_Style
+}
+
+// MarkupError is a markup error at a position
+type MarkupError struct {
+	Pos Position
+	Msg string
+}
+
+func (e *MarkupError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// Parse returns the tree of markup. Errors are returned joined (errors.Join) as *MarkupError, along with a tree where
+// unclosed styles end at the end of markup, and stray closing brackets are ignored.
+func Parse(markup string) (*Node, error) {
+	root := &Node{Kind: NK_Root, Pos: Position{0, 1, 1}}
+	stack := []*Node{root}
+	var errs []error
+	var text strings.Builder
+	var textPos Position
+
+	top := func() *Node { return stack[len(stack)-1] }
+	flush := func() {
+		if text.Len() > 0 {
+			top().Children = append(top().Children, &Node{Kind: NK_Text, Text: text.String(), Pos: textPos})
+			text.Reset()
+		}
+	}
+
+	pos := Position{0, 1, 1}
+	for offset, c := range markup {
+		pos.Offset = offset
+		switch {
+		case c == '\r':
+
+		case c == '\n':
+			flush()
+			top().Children = append(top().Children, &Node{Kind: NK_Newline, Pos: pos})
+			pos.Line++
+			pos.Column = 0
+
+		case c == '¬':
+			flush()
+			top().Children = append(top().Children, &Node{Kind: NK_Margin, Pos: pos})
+
+		case open_style(c) != 0:
+			flush()
+			n := &Node{Kind: NK_Style, Style: open_style(c), Pos: pos}
+			top().Children = append(top().Children, n)
+			stack = append(stack, n)
+
+		case close_style(c) != 0:
+			flush()
+			st := close_style(c)
+			k := len(stack) - 1
+			for k > 0 && stack[k].Style != st {
+				k--
+			}
+			switch {
+			case k == 0:
+				errs = append(errs, &MarkupError{pos, fmt.Sprintf("%c without opening %c", c, style_open_bracket(st))})
+			case k < len(stack)-1:
+				inner := top()
+				errs = append(errs, &MarkupError{pos, fmt.Sprintf("%c closes %c opened at %s while %c opened at %s is not closed",
+					c, style_open_bracket(st), stack[k].Pos, style_open_bracket(inner.Style), inner.Pos)})
+				stack = stack[:k]
+			default:
+				stack = stack[:k]
+			}
+
+		default:
+			if text.Len() == 0 {
+				textPos = pos
+			}
+			text.WriteRune(c)
+		}
+		pos.Column++
+	}
+	flush()
+
+	for k := len(stack) - 1; k > 0; k-- {
+		errs = append(errs, &MarkupError{stack[k].Pos, fmt.Sprintf("%c is not closed", style_open_bracket(stack[k].Style))})
+	}
+	return root, errors.Join(errs...)
+}
+
+// Validate returns an error describing unbalanced brackets of markup, or nil if markup is valid
+func Validate(markup string) error {
+	_, err := Parse(markup)
+	return err
+}
+
+// ValidateTexts validates several markup texts, such as usage and extended usage of a tool, and returns errors of
+// invalid texts joined, prefixed by their name, or nil if all texts are valid
+func ValidateTexts(texts map[string]string) error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(texts)) {
+		if err := Validate(texts[name]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func open_style(c rune) Style {
+	for _, sb := range styleBrackets {
+		if sb.open == c {
+			return sb.style
+		}
+	}
+	return 0
+}
+
+func close_style(c rune) Style {
+	for _, sb := range styleBrackets {
+		if sb.close == c {
+			return sb.style
+		}
+	}
+	return 0
+}
+
+func style_open_bracket(st Style) rune {
+	for _, sb := range styleBrackets {
+		if sb.style == st {
+			return sb.open
+		}
+	}
+	return '?'
+}
+
+// Kinds of tokens of a flattened tree
+type tokenKind int
+
+const (
+	tk_char tokenKind = iota
+	tk_style
+	tk_margin
+	tk_newline
+	tk_end
+)
+
+// token is a character (grapheme cluster), a style change or a marker of a flattened tree
+type token struct {
+	kind  tokenKind
+	text  string // tk_char, a grapheme cluster
+	width int    // tk_char, display width in columns
+	style Style  // tk_style, style turned on or off
+	on    bool   // tk_style
+}
+
+// tokens flattens tree in a sequence of characters and style changes, ending with tk_end, produced while walking the
+// tree so large documents are rendered without a slice of tokens. Characters are grapheme clusters with their display
+// width.
+// A style is only turned off when no enclosing node has the same style, and when a color ends inside another color,
+// the enclosing color is turned on again, since turning a color off restores default color.
+func (n *Node) tokens() iter.Seq[token] {
+	return func(yield func(token) bool) {
+		var active []Style // Styles of enclosing nodes
+		stopped := false
+		emit := func(tok token) {
+			if !stopped && !yield(tok) {
+				stopped = true
+			}
+		}
+
+		is_active := func(st Style) bool {
+			for _, a := range active {
+				if a == st {
+					return true
+				}
+			}
+			return false
+		}
+
+		var walk func(n *Node)
+		walk = func(n *Node) {
+			if stopped {
+				return
+			}
+			switch n.Kind {
+			case NK_Text:
+				// A base character and its combining accents, or an emoji sequence, is a single character of a width
+				// of 0, 1 or 2 columns according to East Asian Width and emoji rules
+				state := -1
+				for rest := n.Text; rest != "" && !stopped; {
+					var cluster string
+					var width int
+					cluster, rest, width, state = uniseg.FirstGraphemeClusterInString(rest, state)
+					emit(token{kind: tk_char, text: cluster, width: width})
+				}
+			case NK_Margin:
+				emit(token{kind: tk_margin})
+			case NK_Newline:
+				emit(token{kind: tk_newline})
+			case NK_Style:
+				if !is_active(n.Style) {
+					emit(token{kind: tk_style, style: n.Style, on: true})
+				}
+				active = append(active, n.Style)
+				for _, child := range n.Children {
+					walk(child)
+				}
+				active = active[:len(active)-1]
+				if !is_active(n.Style) {
+					emit(token{kind: tk_style, style: n.Style, on: false})
+					if n.Style&(ST_Color1|ST_Color2) != 0 {
+						for k := len(active) - 1; k >= 0; k-- {
+							if active[k]&(ST_Color1|ST_Color2) != 0 {
+								emit(token{kind: tk_style, style: active[k], on: true})
+								break
+							}
+						}
+					}
+				}
+			case NK_Root:
+				for _, child := range n.Children {
+					walk(child)
+				}
+			}
+		}
+		walk(n)
+		emit(token{kind: tk_end})
+	}
+}
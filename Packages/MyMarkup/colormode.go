@@ -0,0 +1,72 @@
+// colormode.go
+// Choice between styled (ANSI escape sequences) and plain text rendering, so help redirected to a file or piped to
+// another command doesn't contain escape sequences
+//
+// 2026-10-19	PV 		First version
+
+package MyMarkup
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ColorMode selects whether rendering uses ANSI escape sequences
+type ColorMode int
+
+const (
+	CM_Auto   ColorMode = iota // Styled if stdout is a terminal, NO_COLOR is not set and TERM is not dumb
+	CM_Always                  // Always styled, even if stdout is redirected
+	CM_Never                   // Plain text, styling is removed, wrapping and margins are kept
+)
+
+// Color is the color mode used by BuildMarkup and RenderMarkup. ColorMode implements flag.Value, so tools share
+// option --color=auto|always|never with:
+//
+//	flag.Var(&MyMarkup.Color, "color", "Use colors: auto (default), always or never")
+var Color ColorMode = CM_Auto
+
+func (cm ColorMode) String() string {
+	switch cm {
+	case CM_Auto:
+		return "auto"
+	case CM_Always:
+		return "always"
+	case CM_Never:
+		return "never"
+	}
+	return fmt.Sprintf("ColorMode(%d)", int(cm))
+}
+
+// Set parses auto, always or never, implementing flag.Value
+func (cm *ColorMode) Set(s string) error {
+	switch s {
+	case "auto":
+		*cm = CM_Auto
+	case "always":
+		*cm = CM_Always
+	case "never":
+		*cm = CM_Never
+	default:
+		return fmt.Errorf("invalid color mode %q, use auto, always or never", s)
+	}
+	return nil
+}
+
+// UseColor returns true if output should be styled according to Color mode.
+// In CM_Auto mode, colors are disabled when NO_COLOR environment variable is set to a non-empty value
+// (https://no-color.org/), when TERM is dumb, or when stdout is not a terminal.
+func UseColor() bool {
+	switch Color {
+	case CM_Always:
+		return true
+	case CM_Never:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
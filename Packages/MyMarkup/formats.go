@@ -0,0 +1,361 @@
+// formats.go
+// Rendering of MyMarkup to HTML, CommonMark and roff (man page), so usage texts can be published as web docs and
+// man pages from the same source as terminal help.
+//
+// Styles map to CSS classes mm-bold, mm-italic, mm-underline, mm-color1 and mm-color2 in HTML. In CommonMark, bold and
+// underline are strong emphasis, italic is emphasis, and both colors are code spans. In roff, bold and colors use bold
+// font, italic and underline use italic font. A line containing ¬ is rendered with a hanging indent: a flex box in HTML,
+// a .TP tagged paragraph in roff, and margin text followed by text in CommonMark.
+//
+// 2026-10-19	PV 		First version
+// 2026-10-19	PV 		Lines built from markup tree
+
+package MyMarkup
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// OutputFormat is a format of generated documentation
+type OutputFormat int
+
+const (
+	OF_Terminal OutputFormat = iota // Text for terminal, styled or not according to Color mode
+	OF_HTML                         // HTML page
+	OF_Markdown                     // CommonMark
+	OF_Man                          // roff using man macros
+)
+
+func (of OutputFormat) String() string {
+	switch of {
+	case OF_Terminal:
+		return "terminal"
+	case OF_HTML:
+		return "html"
+	case OF_Markdown:
+		return "md"
+	case OF_Man:
+		return "man"
+	}
+	return fmt.Sprintf("OutputFormat(%d)", int(of))
+}
+
+// Set parses html, md or man, implementing flag.Value for tools hidden option --help-format
+func (of *OutputFormat) Set(s string) error {
+	switch s {
+	case "html":
+		*of = OF_HTML
+	case "md", "markdown":
+		*of = OF_Markdown
+	case "man", "roff":
+		*of = OF_Man
+	default:
+		return fmt.Errorf("invalid help format %q, use html, md or man", s)
+	}
+	return nil
+}
+
+// textRun is a text with a uniform style
+type textRun struct {
+	text  string
+	style Style
+}
+
+// markupLine is a line of markup, with text before ¬ in margin if hanging is true
+type markupLine struct {
+	margin  []textRun
+	text    []textRun
+	hanging bool
+}
+
+// parse_lines splits markup tree into lines of styled runs. Styles remain active across lines until closed.
+// Only the first ¬ of a line is used.
+func parse_lines(txt_str string) []markupLine {
+	tree, _ := Parse(txt_str)
+	var lines []markupLine
+	var line markupLine
+	var style Style
+	for tok := range tree.tokens() {
+		switch tok.kind {
+		case tk_char:
+			if k := len(line.text) - 1; k >= 0 && line.text[k].style == style {
+				line.text[k].text += tok.text
+			} else {
+				line.text = append(line.text, textRun{tok.text, style})
+			}
+		case tk_style:
+			if tok.on {
+				style |= tok.style
+			} else {
+				style &^= tok.style
+			}
+		case tk_margin:
+			if !line.hanging {
+				line.margin, line.text = line.text, nil
+				line.hanging = true
+			}
+		case tk_newline, tk_end:
+			lines = append(lines, line)
+			line = markupLine{}
+		}
+	}
+	return lines
+}
+
+// runs_text returns unstyled text of runs
+func runs_text(runs []textRun) string {
+	var sb strings.Builder
+	for _, r := range runs {
+		sb.WriteString(r.text)
+	}
+	return sb.String()
+}
+
+// BuildDocument returns a complete document in format with name, version and description as title, followed by
+// formatted markup
+func BuildDocument(format OutputFormat, name, version, description, txt_str string) string {
+	switch format {
+	case OF_HTML:
+		return fmt.Sprintf("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n<h1>%s %s</h1>\n<p>%s</p>\n%s</body>\n</html>\n",
+			html.EscapeString(name), HTMLStyleSheet, html.EscapeString(name), html.EscapeString(version), html.EscapeString(description), BuildHTML(txt_str))
+	case OF_Markdown:
+		return fmt.Sprintf("# %s %s\n\n%s\n\n%s", md_escape(name), md_escape(version), md_escape(description), BuildMarkdown(txt_str))
+	case OF_Man:
+		return fmt.Sprintf(".TH %s 1 \"\" \"%s %s\"\n.SH NAME\n%s \\- %s\n.SH DESCRIPTION\n%s",
+			roff_escape(strings.ToUpper(name)), roff_escape(name), roff_escape(version), roff_escape(name), roff_escape(description), BuildRoff(txt_str))
+	}
+	return name + " " + version + "\n" + description + "\n\n" + BuildMarkup(txt_str) + "\n"
+}
+
+// HTMLStyleSheet contains CSS rules for classes generated by BuildHTML
+const HTMLStyleSheet = `.mymarkup { font-family: monospace; }
+.mymarkup .mm-line { white-space: pre-wrap; min-height: 1.2em; }
+.mymarkup .mm-hanging { display: flex; }
+.mymarkup .mm-margin { flex: none; white-space: pre; }
+.mymarkup .mm-bold { font-weight: bold; }
+.mymarkup .mm-italic { font-style: italic; }
+.mymarkup .mm-underline { text-decoration: underline; }
+.mymarkup .mm-color1 { color: darkcyan; }
+.mymarkup .mm-color2 { color: darkgoldenrod; }
+`
+
+// BuildHTML formats markup as an HTML fragment, a div of class mymarkup containing a div per line
+func BuildHTML(txt_str string) string {
+	var sb strings.Builder
+	sb.WriteString("<div class=\"mymarkup\">\n")
+	for _, line := range parse_lines(txt_str) {
+		if line.hanging {
+			sb.WriteString("<div class=\"mm-line mm-hanging\"><span class=\"mm-margin\">")
+			html_runs(&sb, line.margin)
+			sb.WriteString("</span><span class=\"mm-text\">")
+			html_runs(&sb, line.text)
+			sb.WriteString("</span></div>\n")
+		} else {
+			sb.WriteString("<div class=\"mm-line\">")
+			html_runs(&sb, line.text)
+			sb.WriteString("</div>\n")
+		}
+	}
+	sb.WriteString("</div>\n")
+	return sb.String()
+}
+
+func html_runs(sb *strings.Builder, runs []textRun) {
+	for _, r := range runs {
+		if r.style == 0 {
+			sb.WriteString(html.EscapeString(r.text))
+			continue
+		}
+		var classes []string
+		for _, c := range []struct {
+			style Style
+			class string
+		}{{ST_Bold, "mm-bold"}, {ST_Italic, "mm-italic"}, {ST_Underline, "mm-underline"}, {ST_Color1, "mm-color1"}, {ST_Color2, "mm-color2"}} {
+			if r.style&c.style != 0 {
+				classes = append(classes, c.class)
+			}
+		}
+		fmt.Fprintf(sb, "<span class=\"%s\">%s</span>", strings.Join(classes, " "), html.EscapeString(r.text))
+	}
+}
+
+// BuildMarkdown formats markup as CommonMark. Lines are separated by hard line breaks, and leading spaces are removed
+// since they would start an indented code block.
+func BuildMarkdown(txt_str string) string {
+	lines := parse_lines(txt_str)
+	var sb strings.Builder
+	for i, line := range lines {
+		var s string
+		if line.hanging {
+			s = strings.TrimRight(md_runs(line.margin), " ") + " " + strings.TrimLeft(md_runs(line.text), " ")
+		} else {
+			s = md_runs(line.text)
+		}
+		s = strings.TrimSpace(s)
+		sb.WriteString(s)
+		if s != "" && i+1 < len(lines) && strings.TrimSpace(runs_text(lines[i+1].margin)+runs_text(lines[i+1].text)) != "" {
+			// Hard line break, ignored at the end of a block
+			sb.WriteString("  ")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func md_runs(runs []textRun) string {
+	var sb strings.Builder
+	for _, r := range runs {
+		text := r.text
+		if r.style&(ST_Color1|ST_Color2) != 0 {
+			text = md_code(text)
+		} else {
+			text = md_escape(text)
+		}
+
+		delim := ""
+		if r.style&(ST_Bold|ST_Underline) != 0 {
+			delim += "**"
+		}
+		if r.style&ST_Italic != 0 {
+			delim += "*"
+		}
+		if delim == "" || strings.TrimSpace(text) == "" {
+			sb.WriteString(text)
+			continue
+		}
+		// Emphasis delimiters must be adjacent to text, not to spaces
+		core := strings.TrimSpace(text)
+		start := strings.Index(text, core)
+		sb.WriteString(text[:start] + delim + core + delim + text[start+len(core):])
+	}
+	return sb.String()
+}
+
+// md_escape escapes CommonMark punctuation. Hyphens are not escaped, so "- " at the beginning of a line, used for
+// lists in markup, is also a list in CommonMark.
+func md_escape(s string) string {
+	var sb strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune("\\`*_[]<>#|&", c) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// md_code returns s as a code span, with a backtick string longer than any backtick string of s
+func md_code(s string) string {
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+// BuildRoff formats markup as roff using man macros, to be included in a man page after a section header.
+// Lines with ¬ are tagged paragraphs, or bulleted paragraphs when margin is "- ".
+func BuildRoff(txt_str string) string {
+	var sb strings.Builder
+	inTP := false
+	for _, line := range parse_lines(txt_str) {
+		switch {
+		case line.hanging && strings.TrimSpace(runs_text(line.margin)) == "-":
+			// List item
+			sb.WriteString(".IP \\(bu 2\n")
+			sb.WriteString(roff_line(trim_runs(line.text)))
+			inTP = true
+
+		case line.hanging:
+			sb.WriteString(".TP\n")
+			sb.WriteString(roff_line(trim_runs(line.margin)))
+			sb.WriteString(roff_line(trim_runs(line.text)))
+			inTP = true
+
+		case strings.TrimSpace(runs_text(line.text)) == "":
+			if inTP {
+				// Ends tagged paragraphs with a vertical space
+				sb.WriteString(".PP\n")
+				inTP = false
+			} else {
+				sb.WriteString(".sp\n")
+			}
+
+		default:
+			if inTP {
+				sb.WriteString(".PP\n")
+				inTP = false
+			}
+			sb.WriteString(roff_line(line.text))
+			sb.WriteString(".br\n")
+		}
+	}
+	return sb.String()
+}
+
+// trim_runs removes leading and trailing spaces of runs
+func trim_runs(runs []textRun) []textRun {
+	res := append([]textRun(nil), runs...)
+	for len(res) > 0 {
+		res[0].text = strings.TrimLeft(res[0].text, " ")
+		if res[0].text != "" {
+			break
+		}
+		res = res[1:]
+	}
+	for len(res) > 0 {
+		res[len(res)-1].text = strings.TrimRight(res[len(res)-1].text, " ")
+		if res[len(res)-1].text != "" {
+			break
+		}
+		res = res[:len(res)-1]
+	}
+	return res
+}
+
+// roff_line returns a text line with font changes, ending with regular font and a newline
+func roff_line(runs []textRun) string {
+	var sb strings.Builder
+	font := "R"
+	for _, r := range runs {
+		f := "R"
+		bold := r.style&(ST_Bold|ST_Color1|ST_Color2) != 0
+		italic := r.style&(ST_Italic|ST_Underline) != 0
+		switch {
+		case bold && italic:
+			f = "BI"
+		case bold:
+			f = "B"
+		case italic:
+			f = "I"
+		}
+		if f != font {
+			if len(f) == 1 {
+				sb.WriteString("\\f" + f)
+			} else {
+				sb.WriteString("\\f(" + f)
+			}
+			font = f
+		}
+		sb.WriteString(roff_escape(r.text))
+	}
+	if font != "R" {
+		sb.WriteString("\\fR")
+	}
+	s := sb.String()
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		// Not a control line
+		s = "\\&" + s
+	}
+	return s + "\n"
+}
+
+// roff_escape escapes backslashes and hyphens, so options such as -v are not hyphenated or converted to dashes
+func roff_escape(s string) string {
+	return strings.NewReplacer("\\", "\\e", "-", "\\-").Replace(s)
+}
@@ -3,6 +3,7 @@ module github.com/PieVio/MyMarkup
 go 1.24.3
 
 require (
+	github.com/rivo/uniseg v0.4.7
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/term v0.32.0 // direct
 )
@@ -3,6 +3,12 @@
 //
 // 2025-07-02	PV 		First version
 // 2025-07-05	PV 		1.1.0 Generation now produces a string rather rhan direct printing
+// 2026-10-19	PV 		1.2.0 Plain text rendering without escape sequences, selected by Color mode (colormode.go)
+// 2026-10-19	PV 		1.3.0 HTML, CommonMark and roff renderers (formats.go)
+// 2026-10-19	PV 		1.4.0 Parse to a tree with errors positions, Validate, renderers use the tree (ast.go)
+// 2026-10-19	PV 		1.4.1 Wrapping uses display width of grapheme clusters (wide characters, combining accents, emoji); no | after a cut word without showLimits
+// 2026-10-19	PV 		1.5.0 Render streams to an io.Writer with explicit width, in linear time; Build* functions are wrappers
+// 2026-10-19	PV 		1.5.1 ValidateTexts validates all usage texts of a tool (ast.go)
 //
 // MyMarkup use pecialized brackets for formatting text:
 // ⟪Bold⟫           ~W  ~X
@@ -17,7 +23,9 @@
 package MyMarkup
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -25,7 +33,7 @@ import (
 )
 
 const (
-	libVersion = "1.1.0"
+	libVersion = "1.5.1"
 )
 
 const (
@@ -86,140 +94,135 @@ const (
 	BG_BRIGHT_WHITE   = "\x1b[107m"
 )
 
-const END_OF_STRING = '£'
-
 // Version returns the library version.
 func Version() string {
 	return libVersion
 }
 
+// RenderOptions controls Render
+type RenderOptions struct {
+	Width      int  // Width in columns, 0 for stdout terminal width (80 if stdout is not a terminal)
+	ShowLimits bool // Line of dashes above text and | at width, for tests
+	Color      bool // Styles with ANSI escape sequences
+}
+
+// RenderMarkup prints markup to stdout, styled or plain depending on Color mode
 func RenderMarkup(txt_string string) {
-	fmt.Println(BuildMarkup(txt_string))
+	Render(os.Stdout, txt_string, RenderOptions{Color: UseColor()})
+	fmt.Println()
 }
 
+// BuildMarkup formats markup for stdout width, styled or plain depending on Color mode
 func BuildMarkup(txt_str string) string {
-	width := get_terminal_width()
-	return BuildMarkupCore(txt_str, false, width)
+	return build_markup(txt_str, RenderOptions{Color: UseColor()})
 }
 
+// BuildMarkupCore formats markup with ANSI escape sequences
 func BuildMarkupCore(txt_str string, showLimits bool, width int) string {
-	// Add END_OF_STRING special char
-	txt_str += string(END_OF_STRING)
+	return build_markup(txt_str, RenderOptions{Width: width, ShowLimits: showLimits, Color: true})
+}
 
-	res := ""
+// BuildPlainMarkupCore formats markup as BuildMarkupCore, without styling
+func BuildPlainMarkupCore(txt_str string, showLimits bool, width int) string {
+	return build_markup(txt_str, RenderOptions{Width: width, ShowLimits: showLimits})
+}
 
-	if showLimits {
-		for i := 0; i < width; i++ {
-			res += "-"
+func build_markup(txt_str string, opts RenderOptions) string {
+	var sb strings.Builder
+	// Writing to a strings.Builder can't fail
+	Render(&sb, txt_str, opts)
+	return sb.String()
+}
+
+// Render writes formatted markup to w through a buffer, in a time linear to markup length, and returns the first
+// write error
+func Render(w io.Writer, txt_str string, opts RenderOptions) error {
+	// Rendered from tree, so unbalanced brackets don't leave styles on
+	tree, _ := Parse(txt_str)
+
+	width := opts.Width
+	if width <= 0 {
+		width = get_terminal_width()
+	}
+	showLimits := opts.ShowLimits
+
+	res := bufio.NewWriter(w)
+	pad := func(n int) {
+		for i := 0; i < n; i++ {
+			res.WriteByte(' ')
+		}
+	}
+	// fill pads current line up to width, col is updated
+	fill := func(col *int) {
+		if *col < width {
+			pad(width - *col)
+			*col = width
 		}
-		res += "\n"
 	}
 
+	if showLimits {
+		res.WriteString(strings.Repeat("-", width))
+		res.WriteString("\n")
+	}
+
+	// Current word is accumulated until a space or end of line, it's never longer than width
 	word := ""
 	len := 0
 
 	col := 0
 	tab := 0
-	for _, c := range txt_str {
-
-		switch c {
-		case '⟪':
-			word += STYLE_BOLD_ON
-			continue
-		case '⟫':
-			word += STYLE_BOLD_OFF
-			continue
-
-		case '⟨':
-			word += STYLE_ITALIC_ON
-			continue
-
-		case '⟩':
-			word += STYLE_ITALIC_OFF
-			continue
-
-		case '⌊':
-			word += STYLE_UNDERLINE_ON
-			continue
-
-		case '⌋':
-			word += STYLE_UNDERLINE_OFF
-			continue
-
-		case '⟦':
-			word += FG_CYAN
-			continue
-
-		case '⟧':
-			word += FG_DEFAULT
-			continue
-
-		case '⦃':
-			word += FG_YELLOW
-			continue
+	for tok := range tree.tokens() {
 
-		case '⦄':
-			word += FG_DEFAULT
-			continue
-
-		case '\r':
+		if tok.kind == tk_style {
+			// Styles are only added in color mode, they don't count in length anyway
+			if opts.Color {
+				word += style_sequence(tok.style, tok.on)
+			}
 			continue
+		}
 
-		case '\n', END_OF_STRING:
+		switch {
+		case tok.kind == tk_newline || tok.kind == tk_end:
 			if word != "" && !is_only_spaces(&word) {
 				if col+len <= width {
-					res += word
+					res.WriteString(word)
 					if showLimits {
 						col += len
-						for col < width {
-							col += 1
-							res += " "
-						}
-						res += "|"
+						fill(&col)
+						res.WriteString("|")
 					}
-					if c == '\n' {
-						res += "\n"
+					if tok.kind == tk_newline {
+						res.WriteString("\n")
 					}
 				} else {
 					if showLimits {
-						for col < width {
-							col += 1
-							res += " "
-						}
-						res += "|"
-					}
-					res += "\n"
-					for i := 0; i < tab; i++ {
-						res += " "
+						fill(&col)
+						res.WriteString("|")
 					}
+					res.WriteString("\n")
+					pad(tab)
 					for strings.HasPrefix(word, " ") {
 						word = word[1:]
 						len -= 1
 					}
-					res += word
+					res.WriteString(word)
 					col = tab + len
 					if showLimits {
-						for col < width {
-							col += 1
-							res += " "
-						}
-						res += "|"
+						fill(&col)
+						res.WriteString("|")
 					}
-					if c == '\n' {
-						res += "\n"
+					if tok.kind == tk_newline {
+						res.WriteString("\n")
 					}
 
 				}
 			} else {
-				for col < width {
-					col += 1
-					res += " "
-				}
+				fill(&col)
 				if showLimits {
-					res += "|"
+					res.WriteString("|")
 				}
-				if c == '\n' {
-					res += "\n"
+				if tok.kind == tk_newline {
+					res.WriteString("\n")
 				}
 
 			}
@@ -228,45 +231,40 @@ func BuildMarkupCore(txt_str string, showLimits bool, width int) string {
 			col = 0
 			tab = 0
 
-		case '¬':
-			res += word
+		case tok.kind == tk_margin:
+			res.WriteString(word)
 			col += len
 			tab = col
 			word = ""
 			len = 0
 
-		case ' ':
+		case tok.kind == tk_char && tok.text == " ":
 			if word != "" {
 				if is_only_spaces(&word) {
-					word += string(c)
+					word += tok.text
 					len += 1
 					continue
 				}
 
 				if col+len <= width {
-					res += word
+					res.WriteString(word)
 					col += len
 					word = ""
 					len = 0
 				} else {
 					if showLimits {
-						for col < width {
-							col += 1
-							res += " "
-						}
-						res += "|"
-					}
-					res += "\n"
-					for i := 0; i < tab; i++ {
-						res += " "
+						fill(&col)
+						res.WriteString("|")
 					}
+					res.WriteString("\n")
+					pad(tab)
 					col = tab
 					for strings.HasPrefix(word, " ") {
 						word = word[1:]
 						len -= 1
 					}
 
-					res += word
+					res.WriteString(word)
 					col += len
 					word = ""
 					len = 0
@@ -276,22 +274,17 @@ func BuildMarkupCore(txt_str string, showLimits bool, width int) string {
 			len += 1
 
 		default:
-			if tab+len >= width-1 {
+			if tab+len+tok.width > width-1 {
 				// We can't accumulate char, it would be longer than width
 				if col > tab {
 					// if we have already printed some chars, we need to flush and start a new line
 					if showLimits {
-						for col < width {
-							col += 1
-							res += " "
-						}
-						res += "|"
+						fill(&col)
+						res.WriteString("|")
 					}
-					res += "\n"
+					res.WriteString("\n")
 
-					for i := 0; i < tab; i++ {
-						res += " "
-					}
+					pad(tab)
 					col = tab
 				}
 
@@ -300,24 +293,53 @@ func BuildMarkupCore(txt_str string, showLimits bool, width int) string {
 					len -= 1
 				}
 
-				if col+len >= width {
-					res += word + "|\n"
+				if col+len+tok.width > width {
+					// Word is cut, a wide character may leave a free column
+					res.WriteString(word)
+					if showLimits {
+						col += len
+						fill(&col)
+						res.WriteString("|")
+					}
+					res.WriteString("\n")
 
 					word = ""
 					len = 0
-					for i := 0; i < tab; i++ {
-						res += " "
-					}
+					pad(tab)
 					col = tab
 				}
 			}
 
-			word += string(c)
-			len += 1
+			word += tok.text
+			len += tok.width
 		}
 	}
 
-	return res
+	return res.Flush()
+}
+
+// style_sequence returns ANSI escape sequence turning style on or off
+func style_sequence(st Style, on bool) string {
+	switch st {
+	case ST_Bold:
+		return choose(on, STYLE_BOLD_ON, STYLE_BOLD_OFF)
+	case ST_Italic:
+		return choose(on, STYLE_ITALIC_ON, STYLE_ITALIC_OFF)
+	case ST_Underline:
+		return choose(on, STYLE_UNDERLINE_ON, STYLE_UNDERLINE_OFF)
+	case ST_Color1:
+		return choose(on, FG_CYAN, FG_DEFAULT)
+	case ST_Color2:
+		return choose(on, FG_YELLOW, FG_DEFAULT)
+	}
+	return ""
+}
+
+func choose(cond bool, ifTrue, ifFalse string) string {
+	if cond {
+		return ifTrue
+	}
+	return ifFalse
 }
 
 func is_only_spaces(txt_str *string) bool {
@@ -2,10 +2,21 @@
 // Tests for MyMarkup package
 //
 // 2025-07-05	PV 		First version
+// 2026-10-19	PV 		Tests for plain rendering and color modes
+// 2026-10-19	PV 		Tests for HTML, CommonMark and roff renderers
+// 2026-10-19	PV 		Tests for Parse and Validate
+// 2026-10-19	PV 		Tests for display width of wide, combining and emoji characters
+// 2026-10-19	PV 		Tests for Render, benchmark of rendering time versus document size
 
 package MyMarkup
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -117,4 +128,267 @@ petite.   |`
     if s!=expected {
 		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, s)
 	}
-}
\ No newline at end of file
+}
+
+func TestPlain(t *testing.T) {
+	text := "⌊Options⌋:\n⦃-a+⦄|⦃-a-⦄  ¬Enable (default) or disable ⟪glob⟫ ⟨autorecurse⟩ mode, see ⟦**⟧ in extended usage\n⦃-v⦄       ¬Verbose output"
+
+	// Plain text is styled text without escape sequences, with the same wrapping and hanging indents
+	escapes := regexp.MustCompile("\x1b\\[[0-9;]*m")
+	for _, width := range []int{80, 40, 25} {
+		styled := BuildMarkupCore(text, true, width)
+		plain := BuildPlainMarkupCore(text, true, width)
+		if escapes.MatchString(plain) {
+			t.Errorf("Width %d: escape sequence in plain text:\n%s", width, plain)
+		}
+		if expected := escapes.ReplaceAllString(styled, ""); plain != expected {
+			t.Errorf("Width %d, expected:\n%s\n\nGot:\n%s", width, expected, plain)
+		}
+	}
+}
+
+func TestColorMode(t *testing.T) {
+	defer func(cm ColorMode) { Color = cm }(Color)
+
+	for _, s := range []string{"auto", "always", "never"} {
+		var cm ColorMode
+		if err := cm.Set(s); err != nil || cm.String() != s {
+			t.Errorf("Set(%q): got %s, err %v", s, cm, err)
+		}
+	}
+	var cm ColorMode
+	if err := cm.Set("yes"); err == nil {
+		t.Errorf("Set(\"yes\"): no error")
+	}
+
+	// Tests don't run with stdout on a terminal, except explicitly run so
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+	Color = CM_Always
+	if !UseColor() {
+		t.Errorf("CM_Always: no color")
+	}
+	Color = CM_Never
+	if UseColor() {
+		t.Errorf("CM_Never: color")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	Color = CM_Auto
+	if UseColor() {
+		t.Errorf("CM_Auto with NO_COLOR: color")
+	}
+	Color = CM_Always
+	if !UseColor() {
+		t.Errorf("CM_Always with NO_COLOR: no color")
+	}
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "dumb")
+	Color = CM_Auto
+	if UseColor() {
+		t.Errorf("CM_Auto with TERM=dumb: color")
+	}
+}
+
+func TestFormats(t *testing.T) {
+	text := "⌊Options⌋:\n⦃-v⦄       ¬Verbose ⟨output⟩ to <stderr> & [log]\n\n- ¬⟪Bold ⟨and italic⟩⟫ \\n\n.dot"
+
+	expectedHTML := `<div class="mymarkup">
+<div class="mm-line"><span class="mm-underline">Options</span>:</div>
+<div class="mm-line mm-hanging"><span class="mm-margin"><span class="mm-color2">-v</span>       </span><span class="mm-text">Verbose <span class="mm-italic">output</span> to &lt;stderr&gt; &amp; [log]</span></div>
+<div class="mm-line"></div>
+<div class="mm-line mm-hanging"><span class="mm-margin">- </span><span class="mm-text"><span class="mm-bold">Bold </span><span class="mm-bold mm-italic">and italic</span> \n</span></div>
+<div class="mm-line">.dot</div>
+</div>
+`
+	if s := BuildHTML(text); s != expectedHTML {
+		t.Errorf("HTML, expected:\n%s\n\nGot:\n%s", expectedHTML, s)
+	}
+
+	expectedMarkdown := "**Options**:  \n`-v` Verbose *output* to \\<stderr\\> \\& \\[log\\]\n\n- **Bold** ***and italic*** \\\\n  \n.dot\n"
+	if s := BuildMarkdown(text); s != expectedMarkdown {
+		t.Errorf("Markdown, expected:\n%s\n\nGot:\n%s", expectedMarkdown, s)
+	}
+
+	expectedRoff := `\fIOptions\fR:
+.br
+.TP
+\fB\-v\fR
+Verbose \fIoutput\fR to <stderr> & [log]
+.PP
+.IP \(bu 2
+\fBBold \f(BIand italic\fR \en
+.PP
+\&.dot
+.br
+`
+	if s := BuildRoff(text); s != expectedRoff {
+		t.Errorf("Roff, expected:\n%s\n\nGot:\n%s", expectedRoff, s)
+	}
+
+	var of OutputFormat
+	if err := of.Set("man"); err != nil || of != OF_Man {
+		t.Errorf("Set(\"man\"): got %s, err %v", of, err)
+	}
+	if err := of.Set("pdf"); err == nil {
+		t.Errorf("Set(\"pdf\"): no error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tree, err := Parse("⌊Usage⌋: ¬⟪a ⦃-v⦄⟫\nb")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	// Compact representation of tree
+	var dump func(n *Node) string
+	dump = func(n *Node) string {
+		switch n.Kind {
+		case NK_Text:
+			return fmt.Sprintf("%q@%d:%d", n.Text, n.Pos.Line, n.Pos.Column)
+		case NK_Style, NK_Root:
+			var children []string
+			for _, child := range n.Children {
+				children = append(children, dump(child))
+			}
+			if n.Kind == NK_Style {
+				return fmt.Sprintf("Style%d@%d:%d(%s)", n.Style, n.Pos.Line, n.Pos.Column, strings.Join(children, " "))
+			}
+			return strings.Join(children, " ")
+		}
+		return n.Kind.String()
+	}
+	expected := `Style4@1:1("Usage"@1:2) ": "@1:8 Margin Style1@1:11("a "@1:12 Style16@1:14("-v"@1:15)) Newline "b"@2:1`
+	if s := dump(tree); s != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, s)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		markup string
+		errors []string
+	}{
+		{"⌊Options⌋:\n⦃-v⦄ ¬Verbose ⟨output⟩", nil},
+		{"⟪Bold ⟪nested⟫ still bold⟫", nil},
+		{"Unclosed ⟦color\nand ⟪bold", []string{"line 2, column 5: ⟪ is not closed", "line 1, column 10: ⟦ is not closed"}},
+		{"Stray ⟧", []string{"line 1, column 7: ⟧ without opening ⟦"}},
+		{"⟪a ⟨b⟫ c⟩", []string{"line 1, column 6: ⟫ closes ⟪ opened at line 1, column 1 while ⟨ opened at line 1, column 4 is not closed",
+			"line 1, column 9: ⟩ without opening ⟨"}},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.markup)
+		var got []string
+		if err != nil {
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var me *MarkupError
+				if !errors.As(e, &me) {
+					t.Errorf("%q: error %v is not a MarkupError", tt.markup, e)
+				}
+				got = append(got, e.Error())
+			}
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.errors, "\n") {
+			t.Errorf("%q, expected errors:\n%s\nGot:\n%s", tt.markup, strings.Join(tt.errors, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestUnbalancedRendering(t *testing.T) {
+	// Unclosed styles are turned off at the end, stray closing brackets are ignored
+	s := BuildMarkupCore("⟪Bold ⟦cyan⟧ and ⦃yellow ⟦cyan⟧ yellow", false, 80)
+	expected := STYLE_BOLD_ON + "Bold " + FG_CYAN + "cyan" + FG_DEFAULT + " and " + FG_YELLOW + "yellow " + FG_CYAN + "cyan" + FG_DEFAULT + FG_YELLOW + " yellow" + FG_DEFAULT + STYLE_BOLD_OFF
+	if s != expected {
+		t.Errorf("Expected:\n%q\nGot:\n%q", expected, s)
+	}
+
+	if s := BuildPlainMarkupCore("Stray ⟫ bracket", false, 80); s != "Stray  bracket" {
+		t.Errorf("Stray bracket: got %q", s)
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		text     string
+		width    int
+		expected string
+	}{
+		// East Asian wide characters use 2 columns, a character that doesn't fit goes to next line
+		{"日本語のテキストを折り返す例です。", 19, `-------------------
+日本語のテキストを |
+折り返す例です。   |`},
+
+		// French text in NFD, accents are combining characters of width 0
+		{"Ce\u0301cile a e\u0301te\u0301 tre\u0300s contente de ce re\u0301sultat", 20, `--------------------
+Ce\u0301cile a e\u0301te\u0301 tre\u0300s   |
+contente de ce      |
+re\u0301sultat            |`},
+
+		// Emoji with skin tone, ZWJ sequence and flag are single characters of 2 columns, hanging indent is kept
+		{"-e   ¬Emoji 👍🏽 and 👨\u200d👩\u200d👧 family 🇫🇷 flag are two columns wide", 24, `------------------------
+-e   Emoji 👍🏽 and 👨\u200d👩\u200d👧    |
+     family 🇫🇷 flag are |
+     two columns wide   |`},
+	}
+
+	for _, tt := range tests {
+		// Raw strings can't contain escapes
+		expected := strings.NewReplacer("\\u0301", "\u0301", "\\u0300", "\u0300", "\\u200d", "\u200d").Replace(tt.expected)
+		if s := BuildPlainMarkupCore(tt.text, true, tt.width); s != expected {
+			t.Errorf("Width %d, expected:\n%s\n\nGot:\n%s", tt.width, expected, s)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRender(t *testing.T) {
+	text := "⟪Usage⟫: ggrep [⦃?⦄|⦃-h⦄|⦃??⦄] [⦃-i⦄] pattern source...\n⦃-i⦄   ¬Ignore case, a long description that is wrapped with a hanging indent"
+
+	var buf bytes.Buffer
+	if err := Render(&buf, text, RenderOptions{Width: 40, ShowLimits: true, Color: true}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if expected := BuildMarkupCore(text, true, 40); buf.String() != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	Render(&buf, text, RenderOptions{Width: 40})
+	if expected := BuildPlainMarkupCore(text, false, 40); buf.String() != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, buf.String())
+	}
+
+	if err := Render(failingWriter{}, text, RenderOptions{Width: 40}); err == nil || err.Error() != "disk full" {
+		t.Errorf("Expected write error, got %v", err)
+	}
+}
+
+// large_markup returns a document of about size bytes made of styled paragraphs with hanging indents
+func large_markup(size int) string {
+	paragraph := "⟪Option⟫ ⦃-x⦄ ¬Une description ⟨assez longue⟩ pour être ⌊coupée⌋ sur plusieurs lignes, avec ⟦des couleurs⟧ et des caractères larges 日本語 ou 👍🏽.\n\n"
+	var sb strings.Builder
+	for sb.Len() < size {
+		sb.WriteString(paragraph)
+	}
+	return sb.String()
+}
+
+// BenchmarkRender renders documents from 64 KB to 1 MB, time per byte (MB/s) is constant since rendering is linear
+func BenchmarkRender(b *testing.B) {
+	for _, size := range []int{64 << 10, 256 << 10, 1 << 20} {
+		text := large_markup(size)
+		b.Run(fmt.Sprintf("%dKB", size>>10), func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				Render(io.Discard, text, RenderOptions{Width: 80, Color: true})
+			}
+		})
+	}
+}
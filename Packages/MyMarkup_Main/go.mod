@@ -7,6 +7,7 @@ replace github.com/PieVio/MyMarkup => C:\Development\GitHub\Go\Packages\MyMarkup
 require github.com/PieVio/MyMarkup v0.0.0-00010101000000-000000000000
 
 require (
+	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/term v0.32.0 // indirect
 )
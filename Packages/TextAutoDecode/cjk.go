@@ -0,0 +1,129 @@
+// cjk.go
+// Detection of legacy multi-byte CJK encodings: Shift_JIS, GB18030, Big5 and EUC-KR
+// These encodings overlap a lot (many byte sequences are valid in several of them), so each candidate is first
+// validated, then ranked using the proportion of decoded characters found in a list of very common characters of the
+// language.
+//
+// 2026-10-19	PV		First version
+// 2026-10-19	PV		cjk_decode decodes by chunks instead of allocating 3 times buffer size
+
+package TextAutoDecode
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+// Very common characters of each language, including hiragana for Japanese and hangul syllables for Korean
+const (
+	commonJapanese = "のにはをたがでてとしれさいるかなもすあうこまらりつくよっんせえおだ" +
+		"ンスルトクイリラシタ日本人大年中一出自事会見行時分上下今何私語月生者前気手社思" +
+		"。、"
+	commonSimplifiedChinese = "的一是在不了有和人这中大为上个国我以要他时来用们生到作地于出就分对成会可主发年动" +
+		"同工也能下过子说产种面而方后多定行学法所民得经十三之进着等部度家电力里如水化高自二理起小物现实加量都两体" +
+		"制机当使点从业本去把性好应开它合还因由其些然前外天政四日那社义事平形相全表间样与关各重新线内数正心反你明" +
+		"看原又么利比或但质气第向道命此变条只没结解问意建月公无系军很情者最立代想已通并提直题党程展五果料象员革位" +
+		"。，"
+	commonTraditionalChinese = "的一是不了人我在有他這個們中來上大為和國地到以說時要就出會可也你對生能而子那得於" +
+		"著下自之年過發後作裡用道行所然家種事成方多經麼去法學如都同現當沒動面起看定天分還進好小部其些主樣理心她本前" +
+		"開但因只從想實日軍者意無力它與長把機十民第公此已工使情明性知全三又關點正業外將兩高間由問很最重並物手應戰向" +
+		"。，"
+	commonKorean = "이다는의에가하고를을지기한서사자리도로있수어대인아해시나게내전정보주일그것들라우니요습만면부제경으말과와" +
+		"저오늘날씨좋책국은입니까했던되며또더같없생각때문람년위"
+)
+
+type cjkCandidate struct {
+	encoding TextFileEncoding
+	decoder  encoding.Encoding
+	common   string
+}
+
+// Candidates in order of preference when ratios are equal
+var cjkCandidates = []cjkCandidate{
+	{TFE_ShiftJIS, japanese.ShiftJIS, commonJapanese},
+	{TFE_GB18030, simplifiedchinese.GB18030, commonSimplifiedChinese},
+	{TFE_Big5, traditionalchinese.Big5, commonTraditionalChinese},
+	{TFE_EUCKR, korean.EUCKR, commonKorean},
+}
+
+// Heuristics constants
+const (
+	minCJKCommonChars = 2    // At least 2 very common characters must be found
+	minCJKCommonRatio = 0.25 // And at least 25% of non-ASCII characters must be very common ones
+)
+
+const cjkChunkSize = 64 * 1024 // Size of buffer receiving each chunk of decoded text
+
+// check_cjk checks if a small byte buffer of n bytes (max SampleSize) is plausible text in one of the CJK encodings.
+// If complete is false, a multi-byte sequence truncated at the end of the buffer is ignored.
+func (d *Decoder) check_cjk(buffer_1000 []byte, n int, complete bool) (TextFileEncoding, decodedText, bool) {
+	bestEncoding, bestText, bestRatio := TFE_NotText, decodedText{}, 0.0
+	for _, c := range cjkCandidates {
+		s, ok := cjk_decode(buffer_1000[:n], c.decoder, complete)
+		if !ok || d.contains_binary_chars(&s.text, true) {
+			continue
+		}
+
+		total, common := 0, 0
+		for _, r := range s.text {
+			if r >= utf8.RuneSelf {
+				total++
+				if strings.ContainsRune(c.common, r) {
+					common++
+				}
+			}
+		}
+		if common < minCJKCommonChars {
+			continue
+		}
+		ratio := float64(common) / float64(total)
+		if ratio >= minCJKCommonRatio && ratio > bestRatio {
+			bestEncoding, bestText, bestRatio = c.encoding, s, ratio
+		}
+	}
+	return bestEncoding, bestText, bestEncoding != TFE_NotText
+}
+
+// cjk_decode decodes buffer with a CJK decoder, and returns false if buffer contains an invalid sequence
+func cjk_decode(buffer []byte, enc encoding.Encoding, atEOF bool) (decodedText, bool) {
+	// Text is decoded by chunks into a builder growing as needed, since a single byte can be decoded as a 3-byte
+	// UTF-8 sequence (half-width katakana, replacement char), but most of text is usually ASCII or 2-byte sequences
+	var tb textBuilder
+	tb.grow(len(buffer))
+	dec := enc.NewDecoder()
+	dst := make([]byte, min(cjkChunkSize, 3*len(buffer)+utf8.UTFMax))
+	for {
+		nDst, nSrc, err := dec.Transform(dst, buffer, atEOF)
+		if bytes.ContainsRune(dst[:nDst], utf8.RuneError) {
+			return decodedText{}, false
+		}
+		tb.write_utf8(dst[:nDst])
+		buffer = buffer[nSrc:]
+		if err == transform.ErrShortDst {
+			continue
+		}
+		// If not at EOF, ErrShortSrc means that last sequence is incomplete, it's just ignored
+		if err != nil && !(err == transform.ErrShortSrc && !atEOF) {
+			return decodedText{}, false
+		}
+		return tb.result(), true
+	}
+}
+
+// cjk_encoding returns x/text encoding for a CJK TextFileEncoding, or nil
+func cjk_encoding(encoding TextFileEncoding) encoding.Encoding {
+	for _, c := range cjkCandidates {
+		if c.encoding == encoding {
+			return c.decoder
+		}
+	}
+	return nil
+}
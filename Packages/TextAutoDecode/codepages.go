@@ -0,0 +1,137 @@
+// codepages.go
+// Detection of the most plausible 8-bit code page
+//
+// 2026-10-19	PV		First version, Windows 1252, CP850, CP437, ISO-8859-15 and Mac Roman candidates
+// 2026-10-19	PV		find_codepage also accepts declared code pages that are not candidates
+// 2026-10-19	PV		Preferred code page for best_codepage
+
+package TextAutoDecode
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+type codePage struct {
+	name    string // IANA name
+	charmap *charmap.Charmap
+}
+
+// Candidate code pages, in order of preference when scores are equal
+var codePages = []codePage{
+	{"windows-1252", charmap.Windows1252},
+	{"IBM850", charmap.CodePage850},
+	{"IBM437", charmap.CodePage437},
+	{"ISO-8859-15", charmap.ISO8859_15},
+	{"macintosh", charmap.Macintosh},
+}
+
+// Accented letters common in French text (also found in English loanwords such as café or naïve)
+const frenchLetters = "éèàçêâîôûùëïüœÉÈÀÇÊÔ"
+
+// best_codepage returns the code page giving the most plausible text for buffer.
+// Only bytes 128..255 are scored, since ASCII part is identical for all candidates.
+// preferred code page, if not "", is returned when no candidate scores better, instead of Windows 1252.
+func best_codepage(buffer []byte, preferred string) codePage {
+	candidates := codePages
+	if cp, ok := find_codepage(preferred); ok && preferred != "" {
+		candidates = []codePage{cp}
+		for _, c := range codePages {
+			if c.charmap != cp.charmap {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+
+	best := candidates[0]
+	bestScore := 0
+	for i, cp := range candidates {
+		score := score_codepage(buffer, cp.charmap)
+		if i == 0 || score > bestScore {
+			best, bestScore = cp, score
+		}
+	}
+	return best
+}
+
+// score_codepage evaluates letter-pair plausibility of characters 128..255 decoded with cm, using their neighbours:
+// accented letters inside words are good, symbols inside words or control characters are bad, and box-drawing
+// characters are only plausible next to other box-drawing characters or spaces.
+func score_codepage(buffer []byte, cm *charmap.Charmap) int {
+	score := 0
+	for i, c := range buffer {
+		if c < 128 {
+			continue
+		}
+
+		r := cm.DecodeByte(c)
+		prev, next := ' ', ' '
+		if i > 0 {
+			prev = cm.DecodeByte(buffer[i-1])
+		}
+		if i+1 < len(buffer) {
+			next = cm.DecodeByte(buffer[i+1])
+		}
+		inWord := unicode.IsLetter(prev) && unicode.IsLetter(next)
+
+		switch {
+		case r == utf8.RuneError || unicode.IsControl(r):
+			score -= 5
+
+		case unicode.IsLetter(r):
+			score++
+			if unicode.IsLetter(prev) || unicode.IsLetter(next) {
+				score++
+			}
+			if strings.ContainsRune(frenchLetters, r) {
+				score += 2
+			}
+			// Uppercase letter after a lowercase letter is unusual
+			if unicode.IsUpper(r) && unicode.IsLower(prev) {
+				score -= 2
+			}
+
+		case is_box_drawing(r):
+			if (is_box_drawing(prev) || unicode.IsSpace(prev)) && (is_box_drawing(next) || unicode.IsSpace(next)) {
+				score += 2
+			} else {
+				score -= 2
+			}
+
+		case inWord:
+			// Symbol or punctuation inside a word
+			score -= 3
+		}
+	}
+	return score
+}
+
+// codepage_charmap returns the charmap of a code page, Windows 1252 if name is unknown
+func codepage_charmap(name string) *charmap.Charmap {
+	cp, _ := find_codepage(name)
+	return cp.charmap
+}
+
+// find_codepage returns a candidate code page, or any 8-bit code page known by IANA index for declared charsets.
+// If name is unknown, Windows 1252 is returned with false.
+func find_codepage(name string) (codePage, bool) {
+	for _, cp := range codePages {
+		if cp.name == name {
+			return cp, true
+		}
+	}
+	if e, err := ianaindex.IANA.Encoding(name); err == nil {
+		if cm, ok := e.(*charmap.Charmap); ok {
+			return codePage{charmap_name(cm), cm}, true
+		}
+	}
+	return codePages[0], false
+}
+
+func is_box_drawing(r rune) bool {
+	return r >= 0x2500 && r <= 0x259F
+}
@@ -0,0 +1,208 @@
+// declared.go
+// Encodings declared in the text itself: XML prolog, HTML meta charset, Python/Emacs/Vim coding cookies, CSS @charset
+// A declaration is only used when it agrees with byte content, it's never trusted blindly since many files are
+// converted or edited without updating their declaration.
+//
+// 2026-10-19	PV		First version
+// 2026-10-19	PV		C1 control characters in text decoded with a declared 8-bit code page are a conflict
+
+package TextAutoDecode
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// TextEncodingSource indicates whether the encoding has been guessed from content or declared in the text
+type TextEncodingSource int
+
+const (
+	TES_Guessed  TextEncodingSource = iota // No declaration found, encoding guessed from content
+	TES_Declared                           // Declared encoding agrees with content, and has been used
+	TES_Conflict                           // Declared encoding is unknown or doesn't match content, guessed encoding has been used
+)
+
+func (src TextEncodingSource) String() string {
+	switch src {
+	case TES_Guessed:
+		return "Guessed"
+	case TES_Declared:
+		return "Declared"
+	case TES_Conflict:
+		return "Conflict"
+	default:
+		return "TES??"
+	}
+}
+
+// Declarations are written in ASCII, so they're searched in text decoded with the guessed encoding
+var (
+	reXMLProlog    = regexp.MustCompile(`\A\s*<\?xml\s[^>]*?\bencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+	reCSSCharset   = regexp.MustCompile(`\A@charset\s+"([A-Za-z0-9._:-]+)"\s*;`)
+	reCodingCookie = regexp.MustCompile(`\A(?:[^\n]*\n)?[^\n]*?(?:#|-\*-|vim?:)[^\n]*?coding[:=][ \t]*([A-Za-z0-9._-]+)`)
+	reHTMLMeta     = regexp.MustCompile(`(?i)<meta\b[^>]*?\bcharset\s*=\s*["']?([A-Za-z0-9._:-]+)`)
+)
+
+// Common names not known by IANA or WHATWG indexes
+var charsetAliases = map[string]string{
+	"utf8":        "utf-8",
+	"latin-1":     "iso-8859-1",
+	"iso-latin-1": "iso-8859-1",
+	"sjis":        "shift_jis",
+	"cp932":       "shift_jis",
+	"gb2312":      "gbk",
+	"cp936":       "gbk",
+	"cp949":       "euc-kr",
+	"cp950":       "big5",
+	"mac-roman":   "macintosh",
+	"ascii":       "us-ascii",
+}
+
+// find_declared_charset returns charset name declared at the beginning of text, or "" if there is none.
+// Coding cookies are only searched in the first two lines, as Python does.
+func find_declared_charset(text string) string {
+	for _, re := range []*regexp.Regexp{reXMLProlog, reCSSCharset, reCodingCookie, reHTMLMeta} {
+		if m := re.FindStringSubmatch(text); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// declared_encoding resolves a declared charset name. For 8-bit encodings, codePage is the MIME name of the code page.
+// Returned encoding is TFE_UTF8, TFE_UTF16LE or TFE_UTF32LE for the whole UTF-8, UTF-16 or UTF-32 families.
+func declared_encoding(name string) (encoding TextFileEncoding, codePage string, ok bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	// Emacs adds EOL style to coding system name
+	for _, suffix := range []string{"-unix", "-dos", "-mac"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	if alias, found := charsetAliases[name]; found {
+		name = alias
+	}
+	// UTF-32 is registered by IANA, but without an implementation in the index
+	if strings.HasPrefix(name, "utf-32") || strings.HasPrefix(name, "utf32") {
+		return TFE_UTF32LE, "", true
+	}
+
+	e, err := ianaindex.IANA.Encoding(name)
+	if err != nil || e == nil {
+		e, err = htmlindex.Get(name)
+		if err != nil || e == nil {
+			return TFE_NotText, "", false
+		}
+	}
+
+	if cm, isCharmap := e.(*charmap.Charmap); isCharmap {
+		return TFE_EightBit, charmap_name(cm), true
+	}
+	switch e {
+	case unicode.UTF8:
+		return TFE_UTF8, "", true
+	case japanese.ShiftJIS:
+		return TFE_ShiftJIS, "", true
+	case simplifiedchinese.GB18030, simplifiedchinese.GBK:
+		return TFE_GB18030, "", true
+	case traditionalchinese.Big5:
+		return TFE_Big5, "", true
+	case korean.EUCKR:
+		return TFE_EUCKR, "", true
+	}
+	if n, _ := ianaindex.IANA.Name(e); n == "US-ASCII" {
+		return TFE_ASCII, "", true
+	} else if strings.HasPrefix(n, "UTF-16") {
+		return TFE_UTF16LE, "", true
+	}
+	return TFE_NotText, "", false
+}
+
+// charmap_name returns the MIME name of a charmap, such as ISO-8859-1, or its IANA name if it has no MIME name
+func charmap_name(cm encoding.Encoding) string {
+	if n, err := ianaindex.MIME.Name(cm); err == nil && n != "" {
+		return n
+	}
+	n, _ := ianaindex.IANA.Name(cm)
+	return n
+}
+
+// apply_declared checks charset declared in sniffed text against guessed encoding, and uses declared encoding if
+// bytes are also valid with it
+func (d *Decoder) apply_declared(tad TextAutoDecode, buffer_1000 []byte, complete bool) TextAutoDecode {
+	if tad.Encoding == TFE_NotText || tad.Encoding == TFE_Empty {
+		return tad
+	}
+	name := find_declared_charset(tad.Text)
+	if name == "" {
+		return tad
+	}
+
+	tad.DeclaredCharset = name
+	tad.Source = TES_Conflict
+	declared, codePage, ok := declared_encoding(name)
+	if !ok {
+		return tad
+	}
+
+	switch {
+	case same_unicode_family(declared, tad.Encoding):
+		tad.Source = TES_Declared
+
+	case tad.Encoding == TFE_ASCII && is_ascii_compatible(declared):
+		// Declared encoding will be used if non-ASCII bytes are found after the first SampleSize bytes (MILLE by default)
+		tad.Source = TES_Declared
+
+	case (tad.Encoding == TFE_EightBit || cjk_encoding(tad.Encoding) != nil) &&
+		(declared == TFE_EightBit || cjk_encoding(declared) != nil):
+		if s, ok := d.declared_agrees(buffer_1000, declared, codePage, complete); ok {
+			tad = TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: declared, CodePage: codePage, DeclaredCharset: name, Source: TES_Declared}
+		}
+	}
+	return tad
+}
+
+// declared_agrees decodes buffer with a declared 8-bit or CJK encoding, and returns false if content contradicts it.
+// Bytes 0x80-0x9F decoded as C1 control characters contradict a declared 8-bit code page, they're actually characters
+// of another code page, such as curly quotes and € of Windows 1252 in a file declared as ISO-8859-1.
+func (d *Decoder) declared_agrees(buffer []byte, declared TextFileEncoding, codePage string, complete bool) (decodedText, bool) {
+	var s decodedText
+	var ok bool
+	if declared == TFE_EightBit {
+		s, _, ok = d.eightbit_decode(buffer, codePage)
+		ok = ok && d.is_mostly_ascii(&s.text) && !d.contains_binary_chars(&s.text, true)
+	} else {
+		s, ok = cjk_decode(buffer, cjk_encoding(declared), complete)
+		ok = ok && !d.contains_binary_chars(&s.text, true)
+	}
+	return s, ok
+}
+
+// same_unicode_family returns true if both encodings are UTF-8, UTF-16 or UTF-32 variants
+func same_unicode_family(e1, e2 TextFileEncoding) bool {
+	family := func(e TextFileEncoding) int {
+		switch e {
+		case TFE_UTF8, TFE_UTF8BOM:
+			return 8
+		case TFE_UTF16LE, TFE_UTF16BE, TFE_UTF16LEBOM, TFE_UTF16BEBOM:
+			return 16
+		case TFE_UTF32LE, TFE_UTF32BE, TFE_UTF32LEBOM, TFE_UTF32BEBOM:
+			return 32
+		}
+		return 0
+	}
+	return family(e1) != 0 && family(e1) == family(e2)
+}
+
+// is_ascii_compatible returns true for encodings where ASCII text is encoded unchanged
+func is_ascii_compatible(e TextFileEncoding) bool {
+	return e == TFE_ASCII || e == TFE_UTF8 || e == TFE_EightBit || cjk_encoding(e) != nil
+}
@@ -0,0 +1,231 @@
+// decodefiles.go
+// Concurrent decoding of a batch of files, so disk I/O and decoding of several files overlap. Memory is bounded by
+// a budget of bytes of files being decoded and of decoded text not yet delivered, and results can be delivered in
+// paths order for deterministic output.
+//
+// 2026-10-19	PV		First version
+// 2026-10-19	PV		Budget counts decoded text, and whole budget is reserved for files whose size doesn't bound content
+
+package TextAutoDecode
+
+import (
+	"context"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// Default budget of bytes of files being decoded or waiting to be delivered by DecodeFiles
+const defaultMaxInFlightBytes = 256 * 1024 * 1024
+
+// Budget reserved before reading a file, per byte of file: its bytes, and decoded text, up to 3 times larger for 8-bit
+// and CJK encodings
+const bytesPerFileByte = 4
+
+// Result is a file decoded by DecodeFiles, Err is the error returned by ReadTextFile
+type Result struct {
+	Path string
+	TextAutoDecode
+	Err error
+}
+
+// batchJob is a file of a batch, from dispatch to delivery
+type batchJob struct {
+	index  int // Position in paths, for ordered delivery
+	path   string
+	budget int64 // Bytes of budget held until delivery, reduced to decoded text size once decoded
+	result Result
+}
+
+// DecodeFiles is Decoder.DecodeFiles using DefaultDecoder
+func DecodeFiles(ctx context.Context, paths <-chan string, workers int) <-chan Result {
+	return DefaultDecoder.DecodeFiles(ctx, paths, workers)
+}
+
+// DecodeFiles reads and decodes files received from paths with workers goroutines (runtime.NumCPU() if workers <= 0),
+// and returns a channel of results, closed after the last result once paths is closed.
+// A file is read only when its cost fits in MaxInFlightBytes along with files being decoded or waiting to be delivered:
+// 4 times its size for its bytes and decoded text, reduced to decoded text size once decoded. When size doesn't bound
+// content, for a compressed file with Decompress, or a file of size 0 such as a FIFO or a /proc entry, the whole budget
+// is reserved. A file costing more than the budget is read alone.
+// With Ordered, results are delivered in the order of paths, otherwise as soon as they're available.
+// To stop before the end, cancel ctx: paths is no longer read, and results channel is closed without further results.
+// The Decoder must not be modified before results channel is closed.
+func (d *Decoder) DecodeFiles(ctx context.Context, paths <-chan string, workers int) <-chan Result {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	budget := newByteBudget(d.max_in_flight_bytes())
+	stop := context.AfterFunc(ctx, budget.cancel)
+
+	// Dispatcher: reserves budget in paths order, so in ordered mode, the next file to deliver can always be read
+	jobs := make(chan *batchJob)
+	go func() {
+		defer close(jobs)
+		for index := 0; ; index++ {
+			var path string
+			select {
+			case <-ctx.Done():
+				return
+			case p, ok := <-paths:
+				if !ok {
+					return
+				}
+				path = p
+			}
+
+			n, ok := budget.acquire(d.file_cost(path, budget.total))
+			if !ok {
+				return
+			}
+			select {
+			case jobs <- &batchJob{index: index, path: path, budget: n}:
+			case <-ctx.Done():
+				budget.release(n)
+				return
+			}
+		}
+	}()
+
+	// Workers
+	decoded := make(chan *batchJob)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if ctx.Err() == nil {
+					tad, err := d.ReadTextFile(job.path)
+					job.result = Result{Path: job.path, TextAutoDecode: tad, Err: err}
+					// File bytes have been released, only decoded text is held until delivery
+					if held := min(int64(len(tad.Text)), job.budget); held < job.budget {
+						budget.release(job.budget - held)
+						job.budget = held
+					}
+				}
+				decoded <- job
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(decoded)
+	}()
+
+	// Collector: delivers results, releasing their budget once delivered
+	results := make(chan Result)
+	go func() {
+		defer close(results)
+		defer stop()
+
+		deliver := func(job *batchJob) {
+			if ctx.Err() == nil {
+				select {
+				case results <- job.result:
+				case <-ctx.Done():
+				}
+			}
+			budget.release(job.budget)
+		}
+
+		pending := map[int]*batchJob{}
+		next := 0
+		for job := range decoded {
+			if !d.Ordered {
+				deliver(job)
+				continue
+			}
+			pending[job.index] = job
+			for {
+				job, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				deliver(job)
+				next++
+			}
+		}
+	}()
+
+	return results
+}
+
+// file_cost returns the budget to reserve before reading path, total if its size doesn't bound its content
+func (d *Decoder) file_cost(path string, total int64) int64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		// Error is returned by ReadTextFile without reading anything
+		return 0
+	}
+	if fi.Size() == 0 || d.Decompress && is_compressed_file(path) {
+		return total
+	}
+	return min(bytesPerFileByte*fi.Size(), total)
+}
+
+// is_compressed_file returns true if path starts with a gzip, bzip2 or xz magic number
+func is_compressed_file(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, containerMagicSize)
+	n, _ := io.ReadFull(f, head)
+	return container_kind(head[:n]) != FK_Unknown
+}
+
+func (d *Decoder) max_in_flight_bytes() int64 {
+	if d.MaxInFlightBytes <= 0 {
+		return defaultMaxInFlightBytes
+	}
+	return d.MaxInFlightBytes
+}
+
+// byteBudget is a counting semaphore of bytes that can be canceled
+type byteBudget struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	total    int64
+	used     int64
+	canceled bool
+}
+
+func newByteBudget(total int64) *byteBudget {
+	b := &byteBudget{total: total}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire waits until size bytes are available, and returns the number of bytes acquired, size capped to total
+// budget. Returns false if budget has been canceled.
+func (b *byteBudget) acquire(size int64) (int64, bool) {
+	n := min(size, b.total)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.used+n > b.total && !b.canceled {
+		b.cond.Wait()
+	}
+	if b.canceled {
+		return 0, false
+	}
+	b.used += n
+	return n, true
+}
+
+func (b *byteBudget) release(n int64) {
+	b.mu.Lock()
+	b.used -= n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+func (b *byteBudget) cancel() {
+	b.mu.Lock()
+	b.canceled = true
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
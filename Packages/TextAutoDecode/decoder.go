@@ -0,0 +1,189 @@
+// decoder.go
+// Decoder with tunable heuristics. Package functions ReadTextFile, DecodeReader, DetectFile... use DefaultDecoder,
+// a Decoder can be used instead to change sample size, thresholds or the preferred code page.
+//
+// 2026-10-19	PV		First version
+// 2026-10-19	PV		Kind of non-text content
+// 2026-10-19	PV		Decompress option
+// 2026-10-19	PV		MaxInFlightBytes and Ordered options of DecodeFiles
+
+package TextAutoDecode
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Decoder contains heuristics parameters used to detect and decode text.
+// Fields left to their zero value use default values, so Decoder{} behaves as DefaultDecoder.
+type Decoder struct {
+	SampleSize           int           // Number of bytes sniffed to detect encoding, 1000 by default, at least 64, rounded down to a multiple of 4
+	MinASCIIRatio        float64       // Minimum ratio of ASCII characters of text decoded without BOM as 8-bit, UTF-16 or UTF-32, 0.75 by default
+	MinSizeForNoBOMCheck int           // UTF-16 and UTF-32 without BOM are only checked for samples larger than this, 20 bytes by default, <0 to always check
+	AllowC1              bool          // C1 control characters U+0080..U+009F are accepted in Unicode and CJK text, instead of indicating a binary content
+	FallbackCodePage     string        // Code page preferred for 8-bit text when detection can't decide, such as "IBM850", "" for Windows 1252
+	InvalidPolicy        InvalidPolicy // Processing of invalid sequences of Unicode encodings
+	Decompress           bool          // gzip, bzip2 and xz compressed content is decompressed, and decompressed content is decoded
+	MaxInFlightBytes     int64         // DecodeFiles budget of bytes of files being decoded or waiting to be delivered, 256 MB by default
+	Ordered              bool          // DecodeFiles delivers results in the order of paths
+}
+
+// DefaultDecoder is used by package functions, changing it changes the behavior of ReadTextFile, DetectFile...
+var DefaultDecoder = Decoder{
+	SampleSize:           MILLE,
+	MinASCIIRatio:        minASCIIPercentage,
+	MinSizeForNoBOMCheck: minSizeForUTF16NoBOMCheck,
+}
+
+const minSampleSize = 64
+
+// ReadTextFile reads and decodes the whole file.
+// Files detected as TFE_NotText from their first SampleSize bytes are not read further.
+func (d *Decoder) ReadTextFile(file string) (TextAutoDecode, error) {
+	if err := d.validate(); err != nil {
+		return TextAutoDecode{}, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return TextAutoDecode{}, err
+	}
+	defer f.Close()
+
+	return d.DecodeReader(f)
+}
+
+// DecodeReader reads and decodes r until EOF.
+// If r content is detected as TFE_NotText from its first SampleSize bytes, r is not read further.
+// A decompression error, such as a truncated gzip file, is returned as an error.
+func (d *Decoder) DecodeReader(r io.Reader) (TextAutoDecode, error) {
+	if err := d.validate(); err != nil {
+		return TextAutoDecode{}, err
+	}
+	r, container, err := d.decompress_reader(r)
+	if err != nil {
+		return TextAutoDecode{}, err
+	}
+	head, complete, err := d.read_head(r)
+	if err != nil {
+		return TextAutoDecode{}, err
+	}
+
+	tad, err := d.decode_all(head, complete, func() ([]byte, error) {
+		rest, err := io.ReadAll(r)
+		return append(head, rest...), err
+	})
+	if err != nil {
+		return TextAutoDecode{}, err
+	}
+	tad.Container = container
+	return tad, nil
+}
+
+// DecodeBytes decodes buffer. An unknown FallbackCodePage is ignored, since there is no error to return.
+// With Decompress, invalid compressed content is returned as TFE_NotText, with Kind set to the compression format.
+func (d *Decoder) DecodeBytes(buffer []byte) TextAutoDecode {
+	if d.Decompress {
+		if container := container_kind(buffer[:min(len(buffer), containerMagicSize)]); container != FK_Unknown {
+			tad, err := d.DecodeReader(bytes.NewReader(buffer))
+			if err != nil {
+				return TextAutoDecode{Encoding: TFE_NotText, Kind: container}
+			}
+			return tad
+		}
+	}
+
+	size := d.sample_size()
+	head := buffer[:min(len(buffer), size)]
+	complete := len(buffer) < size
+
+	tad, _ := d.decode_all(head, complete, func() ([]byte, error) { return buffer, nil })
+	return tad
+}
+
+// DetectFile detects file encoding reading at most the first SampleSize bytes
+func (d *Decoder) DetectFile(file string) (TextAutoDetect, error) {
+	if err := d.validate(); err != nil {
+		return TextAutoDetect{Encoding: TFE_FileError, Confidence: TFC_High}, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return TextAutoDetect{Encoding: TFE_FileError, Confidence: TFC_High}, err
+	}
+	defer f.Close()
+
+	return d.DetectReader(f)
+}
+
+// DetectReader detects encoding of r reading at most the first SampleSize bytes
+func (d *Decoder) DetectReader(r io.Reader) (TextAutoDetect, error) {
+	if err := d.validate(); err != nil {
+		return TextAutoDetect{Encoding: TFE_FileError, Confidence: TFC_High}, err
+	}
+	r, container, err := d.decompress_reader(r)
+	if err != nil {
+		return TextAutoDetect{Encoding: TFE_FileError, Confidence: TFC_High}, err
+	}
+	head, complete, err := d.read_head(r)
+	if err != nil {
+		return TextAutoDetect{Encoding: TFE_FileError, Confidence: TFC_High}, err
+	}
+
+	tad := d.sniff(head, complete)
+	encoding := tad.Encoding
+	confidence := TFC_Low
+	switch {
+	case complete:
+		confidence = TFC_High
+	case encoding == TFE_NotText || encoding == TFE_UTF8BOM || encoding == TFE_UTF16LEBOM || encoding == TFE_UTF16BEBOM ||
+		encoding == TFE_UTF32LEBOM || encoding == TFE_UTF32BEBOM:
+		confidence = TFC_High
+	case encoding == TFE_UTF8 || encoding == TFE_UTF16LE || encoding == TFE_UTF16BE || encoding == TFE_UTF32LE || encoding == TFE_UTF32BE:
+		confidence = TFC_Medium
+	case tad.Source == TES_Declared:
+		confidence = TFC_High
+	case cjk_encoding(encoding) != nil:
+		confidence = TFC_Medium
+	}
+	ad := TextAutoDetect{Encoding: encoding, Confidence: confidence, CodePage: tad.CodePage, DeclaredCharset: tad.DeclaredCharset, Source: tad.Source,
+		Container: container}
+	if encoding == TFE_NotText {
+		ad.Kind = file_kind(head)
+	}
+	return ad, nil
+}
+
+// validate checks parameters that can't be silently replaced by a default value
+func (d *Decoder) validate() error {
+	if d.FallbackCodePage != "" {
+		if _, ok := find_codepage(d.FallbackCodePage); !ok {
+			return fmt.Errorf("unknown code page %s", d.FallbackCodePage)
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) sample_size() int {
+	if d.SampleSize <= 0 {
+		return MILLE
+	}
+	return max(d.SampleSize, minSampleSize) &^ 3
+}
+
+func (d *Decoder) min_ascii_ratio() float64 {
+	if d.MinASCIIRatio <= 0 {
+		return minASCIIPercentage
+	}
+	return d.MinASCIIRatio
+}
+
+func (d *Decoder) min_size_for_no_bom_check() int {
+	switch {
+	case d.MinSizeForNoBOMCheck == 0:
+		return minSizeForUTF16NoBOMCheck
+	case d.MinSizeForNoBOMCheck < 0:
+		return 0
+	}
+	return d.MinSizeForNoBOMCheck
+}
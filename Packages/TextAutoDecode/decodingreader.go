@@ -0,0 +1,254 @@
+// decodingreader.go
+// Streaming version of decoding, for files too large to be decoded into a single string
+//
+// 2026-10-19	PV		First version
+// 2026-10-19	PV		UTF-32 support
+// 2026-10-19	PV		8-bit code page detection
+// 2026-10-19	PV		CJK legacy encodings
+// 2026-10-19	PV		Declared charsets
+// 2026-10-19	PV		Lines statistics
+// 2026-10-19	PV		Decoder heuristics
+// 2026-10-19	PV		Decompression
+// 2026-10-19	PV		Declared 8-bit code page only used when window contains no C1 control character
+
+package TextAutoDecode
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
+	"golang.org/x/text/transform"
+)
+
+// Reader returns UTF-8 text decoded incrementally from an underlying reader.
+// Encoding is detected from the first SampleSize bytes (MILLE by default), as ReadTextFile does, but since the rest
+// is not read in advance, the content after these bytes is not checked for binary characters, and invalid sequences
+// are replaced by U+FFFD.
+// Special case: if the first SampleSize bytes are ASCII and an invalid UTF-8 sequence is found later, the following bytes
+// are sniffed again, and if they look like 8-bit or CJK text, decoding continues with this encoding (the ASCII part
+// already returned is identical in all these encodings). For 8-bit encoding, code page is detected from sniffed bytes only.
+// An 8-bit or CJK charset declared in the first SampleSize bytes (XML prolog, HTML meta...) is used instead of sniffing
+// again.
+type Reader struct {
+	br        *bufio.Reader
+	utf8      *utf8Stream // Only for ASCII and UTF-8 encodings, since actual encoding can change while reading
+	encoding  TextFileEncoding
+	codePage  string
+	container FileKind    // Compression format with Decoder.Decompress
+	lines     lineScanner // Statistics of text read so far
+}
+
+// NewDecodingReader detects encoding from the first SampleSize bytes (MILLE by default) of r, and returns a Reader
+// producing UTF-8 text.
+// BOM is not part of the decoded text. If r is not text, returned Reader is nil and encoding is TFE_NotText.
+func NewDecodingReader(r io.Reader) (*Reader, TextFileEncoding, error) {
+	return DefaultDecoder.NewDecodingReader(r)
+}
+
+// NewDecodingReader is the streaming version of DecodeReader, encoding is detected from the first SampleSize bytes of r.
+// InvalidPolicy is not used, invalid sequences are always replaced by U+FFFD.
+func (d *Decoder) NewDecodingReader(r io.Reader) (*Reader, TextFileEncoding, error) {
+	if err := d.validate(); err != nil {
+		return nil, TFE_FileError, err
+	}
+	r, container, err := d.decompress_reader(r)
+	if err != nil {
+		return nil, TFE_FileError, err
+	}
+	head, complete, err := d.read_head(r)
+	if err != nil {
+		return nil, TFE_FileError, err
+	}
+
+	tad := d.sniff(head, complete)
+	encoding := tad.Encoding
+	rd := &Reader{encoding: encoding, codePage: tad.CodePage, container: container}
+	var decoded io.Reader
+	switch encoding {
+	case TFE_NotText:
+		return nil, TFE_NotText, nil
+
+	case TFE_Empty:
+		decoded = bytes.NewReader(nil)
+
+	case TFE_ASCII, TFE_UTF8, TFE_UTF8BOM:
+		if encoding == TFE_UTF8BOM {
+			head = head[len(utf8BOM):]
+		}
+		// Buffer must be able to peek a sample to sniff again
+		src := bufio.NewReaderSize(io.MultiReader(bytes.NewReader(head), r), max(64*1024, d.sample_size()))
+		rd.utf8 = &utf8Stream{src: src, decoder: d, encoding: encoding}
+		if tad.Source == TES_Declared {
+			rd.utf8.declaredCharset = tad.DeclaredCharset
+		}
+		decoded = rd.utf8
+
+	case TFE_UTF16LE, TFE_UTF16LEBOM, TFE_UTF16BE, TFE_UTF16BEBOM:
+		endianness := unicode.LittleEndian
+		if encoding == TFE_UTF16BE || encoding == TFE_UTF16BEBOM {
+			endianness = unicode.BigEndian
+		}
+		if encoding == TFE_UTF16LEBOM || encoding == TFE_UTF16BEBOM {
+			head = head[len(utf16LEBOM):]
+		}
+		// Transformer keeps incomplete surrogate pairs between buffers
+		decoder := unicode.UTF16(endianness, unicode.IgnoreBOM).NewDecoder()
+		decoded = transform.NewReader(io.MultiReader(bytes.NewReader(head), r), decoder)
+
+	case TFE_UTF32LE, TFE_UTF32LEBOM, TFE_UTF32BE, TFE_UTF32BEBOM:
+		endianness := utf32.LittleEndian
+		if encoding == TFE_UTF32BE || encoding == TFE_UTF32BEBOM {
+			endianness = utf32.BigEndian
+		}
+		if encoding == TFE_UTF32LEBOM || encoding == TFE_UTF32BEBOM {
+			head = head[len(utf32LEBOM):]
+		}
+		decoder := utf32.UTF32(endianness, utf32.IgnoreBOM).NewDecoder()
+		decoded = transform.NewReader(io.MultiReader(bytes.NewReader(head), r), decoder)
+
+	case TFE_EightBit:
+		decoded = transform.NewReader(io.MultiReader(bytes.NewReader(head), r), codepage_charmap(tad.CodePage).NewDecoder())
+
+	case TFE_ShiftJIS, TFE_GB18030, TFE_Big5, TFE_EUCKR:
+		decoded = transform.NewReader(io.MultiReader(bytes.NewReader(head), r), cjk_encoding(encoding).NewDecoder())
+	}
+
+	rd.br = bufio.NewReader(decoded)
+	return rd, encoding, nil
+}
+
+// Read reads decoded UTF-8 text
+func (rd *Reader) Read(p []byte) (int, error) {
+	n, err := rd.br.Read(p)
+	scan_lines(&rd.lines, p[:n])
+	return n, err
+}
+
+// ReadRune reads a single decoded rune
+func (rd *Reader) ReadRune() (rune, int, error) {
+	r, size, err := rd.br.ReadRune()
+	if err == nil {
+		var buf [utf8.UTFMax]byte
+		scan_lines(&rd.lines, buf[:utf8.EncodeRune(buf[:], r)])
+	}
+	return r, size, err
+}
+
+// Lines returns EOL and lines statistics of text read so far, complete after Read or ReadRune returned io.EOF
+func (rd *Reader) Lines() LineStats {
+	return rd.lines.result()
+}
+
+// Encoding returns current encoding, that can change from TFE_ASCII to TFE_UTF8, TFE_EightBit or a CJK encoding while reading
+func (rd *Reader) Encoding() TextFileEncoding {
+	if rd.utf8 != nil {
+		return rd.utf8.encoding
+	}
+	return rd.encoding
+}
+
+// CodePage returns IANA name of 8-bit code page, or "" if encoding is not TFE_EightBit
+func (rd *Reader) CodePage() string {
+	if rd.utf8 != nil {
+		return rd.utf8.codePage
+	}
+	return rd.codePage
+}
+
+// Container returns FK_Gzip, FK_Bzip2 or FK_Xz if content is decompressed, FK_Unknown otherwise
+func (rd *Reader) Container() FileKind {
+	return rd.container
+}
+
+// utf8Stream copies valid UTF-8 from src, and switches to 8-bit decoding if needed
+type utf8Stream struct {
+	src      *bufio.Reader
+	decoder  *Decoder // Used to sniff again
+	encoding TextFileEncoding
+	codePage string
+	eightbit io.Reader // Not nil after switching to 8-bit or CJK decoding
+
+	declaredCharset string // Charset declared in the first SampleSize bytes, agreeing with ASCII content
+}
+
+func (u *utf8Stream) Read(p []byte) (int, error) {
+	if u.eightbit != nil {
+		return u.eightbit.Read(p)
+	}
+
+	// Peek at least a complete rune
+	buf, err := u.src.Peek(min(max(len(p), utf8.UTFMax), u.src.Size()))
+	if len(buf) == 0 {
+		return 0, err
+	}
+
+	i := 0
+	for i < len(buf) {
+		if buf[i] < utf8.RuneSelf {
+			if i+1 > len(p) {
+				break
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRune(buf[i:])
+		if r == utf8.RuneError && size <= 1 {
+			// Copy valid part first, a rune truncated at the end of peeked bytes will be complete on next call, and an
+			// invalid sequence will be processed on next call
+			if i > 0 {
+				break
+			}
+			return u.invalid(p)
+		}
+		if i+size > len(p) {
+			break
+		}
+		if u.encoding == TFE_ASCII {
+			u.encoding = TFE_UTF8
+		}
+		i += size
+	}
+
+	if i == 0 {
+		return 0, io.ErrShortBuffer
+	}
+	n := copy(p, buf[:i])
+	_, _ = u.src.Discard(n)
+	return n, nil
+}
+
+// invalid processes an invalid UTF-8 sequence at current position of src
+func (u *utf8Stream) invalid(p []byte) (int, error) {
+	if u.encoding == TFE_ASCII {
+		// Sniff again from here, without the ASCII part already returned
+		window, err := u.src.Peek(u.decoder.sample_size())
+		tad := u.decoder.sniff(window, err != nil)
+		if declared, codePage, ok := declared_encoding(u.declaredCharset); ok && (declared == TFE_EightBit || cjk_encoding(declared) != nil) {
+			if _, agrees := u.decoder.declared_agrees(window, declared, codePage, err != nil); agrees {
+				tad = TextAutoDecode{Encoding: declared, CodePage: codePage}
+			}
+		}
+		if tad.Encoding == TFE_EightBit {
+			u.encoding = TFE_EightBit
+			u.codePage = tad.CodePage
+			u.eightbit = transform.NewReader(u.src, codepage_charmap(tad.CodePage).NewDecoder())
+			return u.eightbit.Read(p)
+		}
+		if enc := cjk_encoding(tad.Encoding); enc != nil {
+			u.encoding = tad.Encoding
+			u.eightbit = transform.NewReader(u.src, enc.NewDecoder())
+			return u.eightbit.Read(p)
+		}
+	}
+
+	if len(p) < utf8.RuneLen(utf8.RuneError) {
+		return 0, io.ErrShortBuffer
+	}
+	_, _ = u.src.Discard(1)
+	return utf8.EncodeRune(p, utf8.RuneError), nil
+}
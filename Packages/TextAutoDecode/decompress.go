@@ -0,0 +1,68 @@
+// decompress.go
+// Transparent decompression of gzip, bzip2 and xz compressed text, such as rotated logs (app.log.1.gz), enabled with
+// Decoder.Decompress. Compressed content is recognized by its magic number, not by file extension.
+//
+// 2026-10-19	PV		First version
+
+package TextAutoDecode
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Bytes needed to recognize a compressed stream
+const containerMagicSize = 10
+
+// container_kind returns FK_Gzip, FK_Bzip2 or FK_Xz if head is the beginning of a compressed stream, FK_Unknown
+// otherwise. Checks are stricter than file_kind, since text can start with "BZh".
+func container_kind(head []byte) FileKind {
+	switch {
+	case bytes.HasPrefix(head, []byte("\x1f\x8b\x08")):
+		// Deflate is the only compression method of gzip
+		return FK_Gzip
+
+	case len(head) >= 10 && bytes.HasPrefix(head, []byte("BZh")) && head[3] >= '1' && head[3] <= '9':
+		// Block size, then magic of first block (π digits), or of end of stream (√π digits) for empty content
+		if magic := string(head[4:10]); magic == "1AY&SY" || magic == "\x17rE8P\x90" {
+			return FK_Bzip2
+		}
+
+	case bytes.HasPrefix(head, []byte("\xfd7zXZ\x00")):
+		return FK_Xz
+	}
+	return FK_Unknown
+}
+
+// decompress_reader returns a reader decompressing r if Decompress is set and r is compressed, and the container
+// kind. Otherwise, returned reader returns r content unchanged.
+func (d *Decoder) decompress_reader(r io.Reader) (io.Reader, FileKind, error) {
+	if !d.Decompress {
+		return r, FK_Unknown, nil
+	}
+
+	br := bufio.NewReader(r)
+	head, err := br.Peek(containerMagicSize)
+	if err != nil && err != io.EOF {
+		return nil, FK_Unknown, err
+	}
+
+	container := container_kind(head)
+	switch container {
+	case FK_Gzip:
+		// Concatenated gzip members are decompressed as a single stream, as gunzip does
+		zr, err := gzip.NewReader(br)
+		return zr, container, err
+	case FK_Bzip2:
+		return bzip2.NewReader(br), container, nil
+	case FK_Xz:
+		xr, err := xz.NewReader(br)
+		return xr, container, err
+	}
+	return br, FK_Unknown, nil
+}
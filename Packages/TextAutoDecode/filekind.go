@@ -0,0 +1,206 @@
+// filekind.go
+// Identification of common binary file formats from their magic numbers, so that callers know what a TFE_NotText
+// file actually contains (for instance a .txt file that is actually a ZIP archive)
+//
+// 2026-10-19	PV		First version
+
+package TextAutoDecode
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// FileKind is the format of a binary file identified by its magic number
+type FileKind int
+
+const (
+	FK_Unknown     FileKind = iota // Not identified, or content is text
+	FK_ELF                         // Linux/Unix executable or library
+	FK_PE                          // Windows executable or DLL (also DOS MZ executables)
+	FK_MachO                       // macOS executable or library, including universal binaries
+	FK_JavaClass                   // Compiled Java class
+	FK_WebAssembly                 // WebAssembly binary module
+	FK_Zip                         // ZIP archive, including JAR, EPUB, OpenDocument...
+	FK_OOXML                       // Office Open XML document (.docx, .xlsx, .pptx), a ZIP archive
+	FK_OLE                         // OLE compound file: legacy Office documents (.doc, .xls, .ppt), .msi
+	FK_7z                          // 7-Zip archive
+	FK_Rar                         // RAR archive
+	FK_Tar                         // POSIX tar archive
+	FK_Gzip                        // gzip compressed file
+	FK_Bzip2                       // bzip2 compressed file
+	FK_Xz                          // xz compressed file
+	FK_Zstd                        // Zstandard compressed file
+	FK_PDF                         // PDF document
+	FK_PNG                         // PNG image
+	FK_JPEG                        // JPEG image
+	FK_GIF                         // GIF image
+	FK_BMP                         // Windows bitmap image
+	FK_TIFF                        // TIFF image
+	FK_WebP                        // WebP image
+	FK_SQLite                      // SQLite 3 database
+	FK_MP3                         // MP3 audio with ID3 tag
+	FK_FLAC                        // FLAC audio
+	FK_Ogg                         // Ogg audio or video
+	FK_WAV                         // WAV audio
+	FK_AVI                         // AVI video
+	FK_MP4                         // ISO base media file: MP4, MOV, HEIC...
+)
+
+func (kind FileKind) String() string {
+	switch kind {
+	case FK_Unknown:
+		return "Unknown"
+	case FK_ELF:
+		return "ELF"
+	case FK_PE:
+		return "PE"
+	case FK_MachO:
+		return "Mach-O"
+	case FK_JavaClass:
+		return "Java class"
+	case FK_WebAssembly:
+		return "WebAssembly"
+	case FK_Zip:
+		return "ZIP"
+	case FK_OOXML:
+		return "OOXML"
+	case FK_OLE:
+		return "OLE"
+	case FK_7z:
+		return "7z"
+	case FK_Rar:
+		return "RAR"
+	case FK_Tar:
+		return "tar"
+	case FK_Gzip:
+		return "gzip"
+	case FK_Bzip2:
+		return "bzip2"
+	case FK_Xz:
+		return "xz"
+	case FK_Zstd:
+		return "Zstandard"
+	case FK_PDF:
+		return "PDF"
+	case FK_PNG:
+		return "PNG"
+	case FK_JPEG:
+		return "JPEG"
+	case FK_GIF:
+		return "GIF"
+	case FK_BMP:
+		return "BMP"
+	case FK_TIFF:
+		return "TIFF"
+	case FK_WebP:
+		return "WebP"
+	case FK_SQLite:
+		return "SQLite"
+	case FK_MP3:
+		return "MP3"
+	case FK_FLAC:
+		return "FLAC"
+	case FK_Ogg:
+		return "Ogg"
+	case FK_WAV:
+		return "WAV"
+	case FK_AVI:
+		return "AVI"
+	case FK_MP4:
+		return "MP4"
+	default:
+		return "FK??"
+	}
+}
+
+// Magic numbers found at the beginning of files, checked in order
+var magicNumbers = []struct {
+	magic string
+	kind  FileKind
+}{
+	{"\x7fELF", FK_ELF},
+	{"\xfe\xed\xfa\xce", FK_MachO},
+	{"\xfe\xed\xfa\xcf", FK_MachO},
+	{"\xce\xfa\xed\xfe", FK_MachO},
+	{"\xcf\xfa\xed\xfe", FK_MachO},
+	{"\x00asm", FK_WebAssembly},
+	{"\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1", FK_OLE},
+	{"7z\xbc\xaf\x27\x1c", FK_7z},
+	{"Rar!\x1a\x07", FK_Rar},
+	{"\x1f\x8b", FK_Gzip},
+	{"BZh", FK_Bzip2},
+	{"\xfd7zXZ\x00", FK_Xz},
+	{"\x28\xb5\x2f\xfd", FK_Zstd},
+	{"%PDF-", FK_PDF},
+	{"\x89PNG\r\n\x1a\n", FK_PNG},
+	{"\xff\xd8\xff", FK_JPEG},
+	{"GIF87a", FK_GIF},
+	{"GIF89a", FK_GIF},
+	{"II*\x00", FK_TIFF},
+	{"MM\x00*", FK_TIFF},
+	{"SQLite format 3\x00", FK_SQLite},
+	{"ID3", FK_MP3},
+	{"fLaC", FK_FLAC},
+	{"OggS", FK_Ogg},
+}
+
+// file_kind identifies binary content from its first bytes, FK_Unknown if format is not recognized
+func file_kind(head []byte) FileKind {
+	for _, m := range magicNumbers {
+		if bytes.HasPrefix(head, []byte(m.magic)) {
+			return m.kind
+		}
+	}
+
+	switch {
+	case bytes.HasPrefix(head, []byte("MZ")):
+		// Windows executables start with a DOS stub, PE header offset is at 0x3C, but DOS executables don't have one
+		return FK_PE
+
+	case bytes.HasPrefix(head, []byte("\xca\xfe\xba\xbe")) && len(head) >= 8:
+		// Shared by Java classes and Mach-O universal binaries: for a class, next 4 bytes are the version, at least
+		// 45 (Java 1.0), for a universal binary, it's the number of architectures, only a few
+		if binary.BigEndian.Uint32(head[4:]) >= 45 {
+			return FK_JavaClass
+		}
+		return FK_MachO
+
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		// OOXML documents contain [Content_Types].xml, usually as the first member
+		if len(head) >= 30 {
+			nameLen := int(binary.LittleEndian.Uint16(head[26:]))
+			if len(head) >= 30+nameLen && string(head[30:30+nameLen]) == "[Content_Types].xml" {
+				return FK_OOXML
+			}
+		}
+		return FK_Zip
+
+	case bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		// Empty ZIP archive
+		return FK_Zip
+
+	case bytes.HasPrefix(head, []byte("BM")) && len(head) >= 14:
+		// Reserved fields of BMP header are null
+		if binary.LittleEndian.Uint32(head[6:]) == 0 {
+			return FK_BMP
+		}
+
+	case bytes.HasPrefix(head, []byte("RIFF")) && len(head) >= 12:
+		switch string(head[8:12]) {
+		case "WEBP":
+			return FK_WebP
+		case "WAVE":
+			return FK_WAV
+		case "AVI ":
+			return FK_AVI
+		}
+
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		return FK_MP4
+
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return FK_Tar
+	}
+	return FK_Unknown
+}
@@ -2,4 +2,7 @@ module github.com/PieVio/TextAutoDecode
 
 go 1.24.3
 
-require golang.org/x/text v0.26.0
+require (
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/text v0.26.0
+)
@@ -0,0 +1,250 @@
+// invalid.go
+// Lenient decoding of Unicode encodings: invalid sequences are replaced by U+FFFD instead of rejecting the encoding,
+// and optionally reported with their offsets
+//
+// 2026-10-19	PV		First version
+
+package TextAutoDecode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// InvalidPolicy controls how invalid sequences of Unicode encodings (UTF-8, UTF-16, UTF-32) are processed
+type InvalidPolicy int
+
+const (
+	IP_Strict  InvalidPolicy = iota // Any invalid sequence rejects the encoding, text is decoded as 8-bit or is not text
+	IP_Replace                      // Invalid sequences are replaced by U+FFFD, and counted in InvalidCount
+	IP_Report                       // Same as IP_Replace, and offsets of invalid sequences are returned in InvalidOffsets
+)
+
+func (policy InvalidPolicy) String() string {
+	switch policy {
+	case IP_Strict:
+		return "Strict"
+	case IP_Replace:
+		return "Replace"
+	case IP_Report:
+		return "Report"
+	default:
+		return "IP??"
+	}
+}
+
+// At most 100 offsets are returned, InvalidCount contains the total count
+const maxInvalidOffsets = 100
+
+// invalidReport collects invalid sequences found while decoding
+type invalidReport struct {
+	report  bool
+	count   int
+	offsets []int64
+}
+
+func (ir *invalidReport) add(offset int) {
+	ir.count++
+	if ir.report && len(ir.offsets) < maxInvalidOffsets {
+		ir.offsets = append(ir.offsets, int64(offset))
+	}
+}
+
+// apply_policy returns strict decoding result if it's valid Unicode text, or if a lenient decoding doesn't make sense.
+// Otherwise, buffer_full is decoded again replacing invalid sequences, using sniffed encoding for UTF-16 and UTF-32,
+// or UTF-8 if content contains more valid UTF-8 multi-byte sequences than invalid bytes.
+func (d *Decoder) apply_policy(buffer_full []byte, sniffed, strict TextAutoDecode, policy InvalidPolicy) TextAutoDecode {
+	if policy == IP_Strict {
+		return strict
+	}
+
+	encoding := sniffed.Encoding
+	if encoding == TFE_NotText {
+		// Sniffing failed because of an invalid sequence after a BOM
+		encoding = bom_encoding(buffer_full)
+	}
+	switch {
+	case is_utf16(encoding) || is_utf32(encoding) || encoding == TFE_UTF8BOM:
+		if strict.Encoding != TFE_NotText {
+			return strict
+		}
+	case strict.Encoding == TFE_NotText || strict.Encoding == TFE_EightBit || cjk_encoding(strict.Encoding) != nil:
+		// A declared 8-bit or CJK encoding has been checked against content
+		if strict.Source == TES_Declared || !mostly_utf8(buffer_full) {
+			return strict
+		}
+		encoding = TFE_UTF8
+	default:
+		return strict
+	}
+
+	ir := invalidReport{report: policy == IP_Report}
+	var text decodedText
+	switch {
+	case is_utf16(encoding):
+		text = utf16_lenient(buffer_full, encoding, &ir)
+	case is_utf32(encoding):
+		text = utf32_lenient(buffer_full, encoding, &ir)
+	default:
+		text = utf8_lenient(buffer_full, encoding, &ir)
+	}
+
+	// Same heuristics as strict decoding
+	without_bom := encoding == TFE_UTF16LE || encoding == TFE_UTF16BE || encoding == TFE_UTF32LE || encoding == TFE_UTF32BE
+	if without_bom && !d.is_mostly_ascii(&text.text) || d.contains_binary_chars(&text.text, true) {
+		return strict
+	}
+
+	tad := TextAutoDecode{Text: text.text, Lines: text.lines, Encoding: encoding, DeclaredCharset: sniffed.DeclaredCharset, Source: sniffed.Source,
+		InvalidCount: ir.count, InvalidOffsets: ir.offsets}
+	if tad.Source != TES_Guessed {
+		tad.Source = TES_Conflict
+		if declared, _, ok := declared_encoding(tad.DeclaredCharset); ok && same_unicode_family(declared, encoding) {
+			tad.Source = TES_Declared
+		}
+	}
+	return tad
+}
+
+// mostly_utf8 returns true if buffer contains more valid UTF-8 multi-byte sequences than invalid bytes
+func mostly_utf8(buffer []byte) bool {
+	valid, invalid := 0, 0
+	for i := 0; i < len(buffer); {
+		if buffer[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(buffer[i:])
+		if r == utf8.RuneError && size == 1 {
+			invalid++
+		} else {
+			valid++
+		}
+		i += size
+	}
+	return valid > invalid
+}
+
+// utf8_lenient decodes UTF-8 buffer (with a BOM for TFE_UTF8BOM), each invalid byte is replaced by U+FFFD
+func utf8_lenient(buffer []byte, encoding TextFileEncoding, ir *invalidReport) decodedText {
+	start := 0
+	if encoding == TFE_UTF8BOM {
+		start = len(utf8BOM)
+	}
+
+	var tb textBuilder
+	tb.grow(len(buffer) - start)
+	for i := start; i < len(buffer); {
+		if buffer[i] < utf8.RuneSelf {
+			tb.write_ascii(buffer[i])
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(buffer[i:])
+		if r == utf8.RuneError && size == 1 {
+			ir.add(i)
+			tb.write_rune(utf8.RuneError)
+		} else {
+			tb.write_utf8(buffer[i : i+size])
+		}
+		i += size
+	}
+	return tb.result()
+}
+
+// utf16_lenient decodes UTF-16 buffer (BOM is skipped), unpaired surrogates and a final odd byte are replaced by U+FFFD
+func utf16_lenient(buffer []byte, encoding TextFileEncoding, ir *invalidReport) decodedText {
+	var order binary.ByteOrder = binary.LittleEndian
+	if encoding == TFE_UTF16BE || encoding == TFE_UTF16BEBOM {
+		order = binary.BigEndian
+	}
+	start := 0
+	if encoding == TFE_UTF16LEBOM || encoding == TFE_UTF16BEBOM {
+		start = len(utf16LEBOM)
+	}
+
+	var tb textBuilder
+	tb.grow(len(buffer) / 2)
+	i := start
+	for ; i+1 < len(buffer); i += 2 {
+		r := rune(order.Uint16(buffer[i:]))
+		if utf16.IsSurrogate(r) {
+			r2 := utf8.RuneError
+			if i+3 < len(buffer) {
+				r2 = rune(order.Uint16(buffer[i+2:]))
+			}
+			if dr := utf16.DecodeRune(r, r2); dr != utf8.RuneError {
+				tb.write_rune(dr)
+				i += 2
+				continue
+			}
+			ir.add(i)
+			r = utf8.RuneError
+		}
+		tb.write_rune(r)
+	}
+	if i < len(buffer) {
+		ir.add(i)
+		tb.write_rune(utf8.RuneError)
+	}
+	return tb.result()
+}
+
+// utf32_lenient decodes UTF-32 buffer (BOM is skipped), invalid code points and final incomplete bytes are replaced by
+// U+FFFD
+func utf32_lenient(buffer []byte, encoding TextFileEncoding, ir *invalidReport) decodedText {
+	var order binary.ByteOrder = binary.LittleEndian
+	if encoding == TFE_UTF32BE || encoding == TFE_UTF32BEBOM {
+		order = binary.BigEndian
+	}
+	start := 0
+	if encoding == TFE_UTF32LEBOM || encoding == TFE_UTF32BEBOM {
+		start = len(utf32LEBOM)
+	}
+
+	var tb textBuilder
+	tb.grow(len(buffer) / 4)
+	i := start
+	for ; i+3 < len(buffer); i += 4 {
+		r := order.Uint32(buffer[i:])
+		if r > unicode.MaxRune || r >= 0xD800 && r < 0xE000 {
+			ir.add(i)
+			tb.write_rune(utf8.RuneError)
+			continue
+		}
+		tb.write_rune(rune(r))
+	}
+	if i < len(buffer) {
+		ir.add(i)
+		tb.write_rune(utf8.RuneError)
+	}
+	return tb.result()
+}
+
+// bom_encoding returns encoding indicated by buffer BOM, or TFE_NotText if there is no BOM
+func bom_encoding(buffer []byte) TextFileEncoding {
+	switch {
+	case bytes.HasPrefix(buffer, utf8BOM):
+		return TFE_UTF8BOM
+	case bytes.HasPrefix(buffer, utf32LEBOM):
+		return TFE_UTF32LEBOM
+	case bytes.HasPrefix(buffer, utf32BEBOM):
+		return TFE_UTF32BEBOM
+	case bytes.HasPrefix(buffer, utf16LEBOM):
+		return TFE_UTF16LEBOM
+	case bytes.HasPrefix(buffer, utf16BEBOM):
+		return TFE_UTF16BEBOM
+	}
+	return TFE_NotText
+}
+
+func is_utf16(encoding TextFileEncoding) bool {
+	return encoding == TFE_UTF16LE || encoding == TFE_UTF16BE || encoding == TFE_UTF16LEBOM || encoding == TFE_UTF16BEBOM
+}
+
+func is_utf32(encoding TextFileEncoding) bool {
+	return encoding == TFE_UTF32LE || encoding == TFE_UTF32BE || encoding == TFE_UTF32LEBOM || encoding == TFE_UTF32BEBOM
+}
@@ -0,0 +1,171 @@
+// linestats.go
+// End of lines and lines statistics of decoded text, returned with the text so callers don't need to scan it again
+//
+// 2026-10-19	PV		First version
+// 2026-10-19	PV		Statistics computed by decoders while building text (textBuilder), no separate scan of decoded text
+
+package TextAutoDecode
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// LineStats contains end of lines and lines statistics of a decoded text
+type LineStats struct {
+	CRLF         int  // Number of Windows EOL \r\n
+	LF           int  // Number of Unix EOL \n
+	CR           int  // Number of old Mac EOL \r
+	Mixed        bool // More than one EOL style is used
+	FinalNewline bool // Text ends with an EOL
+	LineCount    int  // Number of lines, a last line without EOL is counted (wc -l only counts EOLs)
+	LongestLine  int  // Length in characters of the longest line, without EOL
+}
+
+// Style returns the most frequent EOL style, EOL_Keep if text contains no EOL.
+// When counts are equal, CRLF is preferred to LF, and LF to CR.
+func (ls LineStats) Style() EOLStyle {
+	switch {
+	case ls.CRLF == 0 && ls.LF == 0 && ls.CR == 0:
+		return EOL_Keep
+	case ls.CRLF >= ls.LF && ls.CRLF >= ls.CR:
+		return EOL_CRLF
+	case ls.LF >= ls.CR:
+		return EOL_LF
+	default:
+		return EOL_CR
+	}
+}
+
+// lineScanner computes LineStats incrementally, text can be split anywhere between calls to scan_lines
+type lineScanner struct {
+	stats   LineStats
+	lineLen int  // Characters in current line
+	afterCR bool // Last byte was \r
+	lastEOL bool // Last byte was \r or \n
+}
+
+// scan_lines updates sc with a block of UTF-8 text
+func scan_lines[T string | []byte](sc *lineScanner, text T) {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '\n' || c == '\r' {
+			sc.add_eol(c)
+		} else {
+			if utf8.RuneStart(c) {
+				sc.lineLen++
+			}
+			sc.afterCR, sc.lastEOL = false, false
+		}
+	}
+}
+
+// add_rune updates sc with a decoded character
+func (sc *lineScanner) add_rune(r rune) {
+	if r == '\n' || r == '\r' {
+		sc.add_eol(byte(r))
+	} else {
+		sc.lineLen++
+		sc.afterCR, sc.lastEOL = false, false
+	}
+}
+
+// add_eol updates sc with \r or \n
+func (sc *lineScanner) add_eol(c byte) {
+	if c == '\n' {
+		if sc.afterCR {
+			// \r already counted as an EOL
+			sc.stats.CR--
+			sc.stats.CRLF++
+		} else {
+			sc.stats.LF++
+			sc.end_line()
+		}
+	} else {
+		sc.stats.CR++
+		sc.end_line()
+	}
+	sc.afterCR = c == '\r'
+	sc.lastEOL = true
+}
+
+func (sc *lineScanner) end_line() {
+	sc.stats.LineCount++
+	sc.stats.LongestLine = max(sc.stats.LongestLine, sc.lineLen)
+	sc.lineLen = 0
+}
+
+// result returns statistics of text scanned so far
+func (sc *lineScanner) result() LineStats {
+	ls := sc.stats
+	if sc.lineLen > 0 {
+		ls.LineCount++
+		ls.LongestLine = max(ls.LongestLine, sc.lineLen)
+	}
+	ls.FinalNewline = sc.lastEOL
+	styles := 0
+	for _, count := range []int{ls.CRLF, ls.LF, ls.CR} {
+		if count > 0 {
+			styles++
+		}
+	}
+	ls.Mixed = styles > 1
+	return ls
+}
+
+// decodedText is a decoded text with its statistics
+type decodedText struct {
+	text  string
+	lines LineStats
+}
+
+// textBuilder builds decoded text, and computes its LineStats in the same pass
+type textBuilder struct {
+	sb    strings.Builder
+	lines lineScanner
+}
+
+func (tb *textBuilder) grow(n int) {
+	tb.sb.Grow(n)
+}
+
+func (tb *textBuilder) write_rune(r rune) {
+	tb.sb.WriteRune(r)
+	tb.lines.add_rune(r)
+}
+
+// write_ascii writes a byte < 0x80
+func (tb *textBuilder) write_ascii(c byte) {
+	tb.sb.WriteByte(c)
+	tb.lines.add_rune(rune(c))
+}
+
+// write_utf8 writes valid UTF-8 text
+func (tb *textBuilder) write_utf8(p []byte) {
+	tb.sb.Write(p)
+	scan_lines(&tb.lines, p)
+}
+
+func (tb *textBuilder) result() decodedText {
+	return decodedText{tb.sb.String(), tb.lines.result()}
+}
+
+// utf8_decode validates UTF-8 buffer and computes its statistics in a single loop
+func utf8_decode(buffer []byte) (decodedText, bool) {
+	var sc lineScanner
+	for i := 0; i < len(buffer); {
+		c := buffer[i]
+		if c < utf8.RuneSelf {
+			sc.add_rune(rune(c))
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(buffer[i:])
+		if r == utf8.RuneError && size == 1 {
+			return decodedText{}, false
+		}
+		sc.add_rune(r)
+		i += size
+	}
+	return decodedText{string(buffer), sc.result()}, true
+}
@@ -0,0 +1,136 @@
+// sourceoffset.go
+// Mapping of byte offsets in decoded text to byte offsets in the original encoded content, so a match found in Text
+// can be reported or located in the file. The map is built lazily on first call, with a checkpoint every 4 KB of text,
+// so converting an offset only decodes widths of at most 4 KB of text.
+//
+// 2026-10-19	PV		First version
+
+package TextAutoDecode
+
+import "unicode/utf8"
+
+// Text bytes between two checkpoints
+const offsetStep = 4096
+
+// offsetCheckpoint associates the offset of a character in text with its offset in source
+type offsetCheckpoint struct {
+	text   int
+	source int64
+}
+
+// offsetMap contains a checkpoint for the first character starting at or after each multiple of offsetStep
+type offsetMap struct {
+	checkpoints []offsetCheckpoint
+	width       func(r rune, size int) int // Source bytes of a character of size bytes in text
+}
+
+// SourceOffset converts byte offset textOffset in Text into the byte offset in the original content, including BOM.
+// An offset inside a multi-byte character gives the offset of the character, an offset at or after the end of Text
+// gives the size of the original content. With Decoder.Decompress, offsets are in decompressed content.
+// For UTF-8 decoded with IP_Replace or IP_Report policies, U+FFFD characters are counted as an invalid byte.
+// The map is built by the first call, which must not be concurrent with other calls on the same TextAutoDecode.
+func (tad *TextAutoDecode) SourceOffset(textOffset int) int64 {
+	if tad.offsets == nil {
+		tad.offsets = tad.build_offset_map()
+	}
+	om := tad.offsets
+
+	textOffset = max(textOffset, 0)
+	k := min(textOffset/offsetStep, len(om.checkpoints)-1)
+	if om.checkpoints[k].text > textOffset {
+		k--
+	}
+	t, s := om.checkpoints[k].text, om.checkpoints[k].source
+	for t < len(tad.Text) {
+		r, size := utf8.DecodeRuneInString(tad.Text[t:])
+		if t+size > textOffset {
+			break
+		}
+		t += size
+		s += int64(om.width(r, size))
+	}
+	return s
+}
+
+// build_offset_map computes checkpoints for the whole text
+func (tad *TextAutoDecode) build_offset_map() *offsetMap {
+	om := &offsetMap{width: tad.source_width()}
+	start := int64(len(bom_of(tad.Encoding)))
+	om.checkpoints = append(om.checkpoints, offsetCheckpoint{0, start})
+
+	s := start
+	next := offsetStep
+	for t, r := range tad.Text {
+		if t >= next {
+			om.checkpoints = append(om.checkpoints, offsetCheckpoint{t, s})
+			next = (t/offsetStep + 1) * offsetStep
+		}
+		s += int64(om.width(r, utf8.RuneLen(r)))
+	}
+	return om
+}
+
+// source_width returns a function computing the number of source bytes of a decoded character
+func (tad *TextAutoDecode) source_width() func(r rune, size int) int {
+	switch tad.Encoding {
+	case TFE_UTF8, TFE_UTF8BOM:
+		lenient := tad.InvalidCount > 0
+		return func(r rune, size int) int {
+			if lenient && r == utf8.RuneError {
+				return 1
+			}
+			return size
+		}
+
+	case TFE_UTF16LE, TFE_UTF16BE, TFE_UTF16LEBOM, TFE_UTF16BEBOM:
+		return func(r rune, size int) int {
+			if size == 4 {
+				// Surrogate pair
+				return 4
+			}
+			return 2
+		}
+
+	case TFE_UTF32LE, TFE_UTF32BE, TFE_UTF32LEBOM, TFE_UTF32BEBOM:
+		return func(r rune, size int) int { return 4 }
+
+	case TFE_ShiftJIS, TFE_GB18030, TFE_Big5, TFE_EUCKR:
+		// Width of a CJK character is the length of its encoding
+		encoder := cjk_encoding(tad.Encoding).NewEncoder()
+		return func(r rune, size int) int {
+			if r < utf8.RuneSelf {
+				return 1
+			}
+			var src [utf8.UTFMax]byte
+			var dst [8]byte
+			encoder.Reset()
+			nDst, _, err := encoder.Transform(dst[:], src[:utf8.EncodeRune(src[:], r)], true)
+			if err != nil {
+				// Can't happen for text decoded from this encoding
+				return 2
+			}
+			return nDst
+		}
+
+	default:
+		// ASCII and 8-bit code pages: one byte per character
+		return func(r rune, size int) int { return 1 }
+	}
+}
+
+// bom_of returns BOM bytes of encoding, nil for encodings without BOM
+func bom_of(encoding TextFileEncoding) []byte {
+	switch encoding {
+	case TFE_UTF8BOM:
+		return utf8BOM
+	case TFE_UTF16LEBOM:
+		return utf16LEBOM
+	case TFE_UTF16BEBOM:
+		return utf16BEBOM
+	case TFE_UTF32LEBOM:
+		return utf32LEBOM
+	case TFE_UTF32BEBOM:
+		return utf32BEBOM
+	}
+	return nil
+}
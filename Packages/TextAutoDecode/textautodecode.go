@@ -4,25 +4,45 @@
 // This function is also used when exploring a large number of files using ggrep command, and many can be very large binary files
 // that should be skipped by ggrep. Always reading the whole file from the beginning simplifies the code, at the expense of
 // secrious performance degradation of ggprep when the list of processed files contains many binaries (.exe, .obj, .pdb, ...)
+// To avoid this, DetectFile and DetectReader only sniff the first Decoder.SampleSize bytes (MILLE by default), so callers
+// can decode only real text files.
 //
 // 2025-06-23	PV		First version
 // 2025-06-28	Gemini	Some updates, but Gemini totally missed the interest of a partial initial read on code performance
 // 2025-07-02	PV		Moved tests to the main project itself; Added prefix TFE_ to TextFileEncoding constants
 // 2025-07-05	PV		1.0.1 check_utf8 but (keep last char ONLY if buffer_1000 is full)
 // 2025-07-06	PV		1.0.2 fixed check_eightbit that didn't truncate buffer_1000 to the first n characters
+// 2026-10-19	PV		1.1.0 DetectFile and DetectReader, detection only with a confidence level; common sniff function
+// 2026-10-19	PV		1.2.0 DecodeBytes and DecodeReader, to decode stdin, archive members, network bodies...
+// 2026-10-19	PV		1.3.0 NewDecodingReader for streaming decoding of large files (decodingreader.go)
+// 2026-10-19	PV		1.4.0 UTF-32 LE/BE, with and without BOM
+// 2026-10-19	PV		1.5.0 8-bit code page detection among several candidates (codepages.go), reported in CodePage field; 75% ASCII ratio computed on characters instead of bytes
+// 2026-10-19	PV		1.6.0 Detection of CJK legacy multi-byte encodings Shift_JIS, GB18030, Big5 and EUC-KR (cjk.go)
+// 2026-10-19	PV		1.7.0 Encodings declared in text (XML, HTML, coding cookies, CSS) used when they agree with content (declared.go)
+// 2026-10-19	PV		1.8.0 WriteTextFile and Encoder to write text in any supported encoding, with BOM and EOL conversion (writer.go)
+// 2026-10-19	PV		1.9.0 EOL and lines statistics returned with decoded text (linestats.go)
+// 2026-10-19	PV		1.10.0 InvalidPolicy to replace and report invalid sequences of Unicode encodings (invalid.go)
+// 2026-10-19	PV		1.11.0 Decoder with tunable heuristics, package functions use DefaultDecoder (decoder.go)
+// 2026-10-19	PV		1.12.0 Kind of TFE_NotText content identified by magic numbers (filekind.go)
+// 2026-10-19	PV		1.13.0 Decoder.Decompress for gzip, bzip2 and xz compressed text, reported in Container field (decompress.go)
+// 2026-10-19	PV		1.14.0 SourceOffset maps offsets in decoded text to offsets in original content (sourceoffset.go)
+// 2026-10-19	PV		1.15.0 DecodeFiles for concurrent decoding of a batch of files with a bytes budget and optional ordered output (decodefiles.go)
+// 2026-10-19	PV		1.15.1 Lines statistics computed while decoding, no more separate scan of decoded text
+// 2026-10-19	PV		1.15.2 Declared 8-bit code page contradicted by C1 control characters is reported as a conflict
+// 2026-10-19	PV		1.15.3 With a lenient policy, a file whose magic number is a binary format is not read completely
+// 2026-10-19	PV		1.15.4 CJK text decoded by chunks, without allocating 3 times file size up front (cjk.go)
 
 package TextAutoDecode
 
 import (
 	"bytes"
+	"encoding/binary"
 	"io"
-	"os"
+	"unicode"
 	"unicode/utf8"
-
-	"golang.org/x/text/encoding/charmap"
 )
 
-const LIB_VERSION = "1.0.2"
+const LIB_VERSION = "1.15.4"
 
 // Returns library current version
 func Version() string {
@@ -38,19 +58,76 @@ const (
 	TFE_NotText                            // Binary or unrecognized text (for instance contains chars in 0..31 other than \r \n \t)
 	TFE_Empty                              // File is empty
 	TFE_ASCII                              // Only 7-bit characters
-	TFE_EightBit                           // ANSI/Windows 1252 (only this variant is checked)
+	TFE_EightBit                           // 8-bit code page, CodePage holds the winning code page (Windows 1252, CP850, ...)
 	TFE_UTF8                               // Plain UTF-8 without BOM
 	TFE_UTF8BOM                            // Starts with EF BB BF
 	TFE_UTF16LE                            // No BOM but UTF-16 LE detected
 	TFE_UTF16BE                            // No BOM but UTF-16 BE detected
 	TFE_UTF16LEBOM                         // Starts with FF FE (Windows)
-	TFE_UTF16BEBOM                        // Starts with FE FF
+	TFE_UTF16BEBOM                         // Starts with FE FF
+	TFE_UTF32LE                            // No BOM but UTF-32 LE detected
+	TFE_UTF32BE                            // No BOM but UTF-32 BE detected
+	TFE_UTF32LEBOM                         // Starts with FF FE 00 00
+	TFE_UTF32BEBOM                         // Starts with 00 00 FE FF
+	TFE_ShiftJIS                           // Japanese Shift_JIS (Windows code page 932)
+	TFE_GB18030                            // Simplified Chinese GB18030, superset of GBK and GB2312
+	TFE_Big5                               // Traditional Chinese Big5
+	TFE_EUCKR                              // Korean EUC-KR
 )
 
 // Type returned by ReadFile, contains text and encoding
 type TextAutoDecode struct {
 	Text     string
 	Encoding TextFileEncoding
+	CodePage string // For TFE_EightBit, IANA name of detected code page such as "windows-1252" or "IBM850"
+
+	DeclaredCharset string             // Charset declared in text such as XML prolog or HTML meta, "" if none
+	Source          TextEncodingSource // Whether Encoding has been guessed or declared
+
+	Lines LineStats // EOL and lines statistics of Text
+
+	InvalidCount   int     // With IP_Replace and IP_Report policies, number of invalid sequences replaced by U+FFFD
+	InvalidOffsets []int64 // With IP_Report policy, offsets in bytes of the first 100 invalid sequences
+
+	Kind      FileKind // For TFE_NotText, format identified from magic number, FK_Unknown if not recognized
+	Container FileKind // With Decoder.Decompress, FK_Gzip, FK_Bzip2 or FK_Xz for compressed content, FK_Unknown otherwise
+
+	offsets *offsetMap // Built by first call to SourceOffset
+}
+
+// TextFileConfidence indicates how reliable is an encoding returned by DetectFile or DetectReader
+type TextFileConfidence int
+
+const (
+	TFC_Low    TextFileConfidence = iota // Only the beginning has been checked, and it gives little information (ASCII, 8-bit)
+	TFC_Medium                           // Only the beginning has been checked, but it's a strong hint (UTF-8 sequences, UTF-16 without BOM)
+	TFC_High                             // Whole file checked, BOM found, or binary content found
+)
+
+func (conf TextFileConfidence) String() string {
+	switch conf {
+	case TFC_Low:
+		return "Low"
+	case TFC_Medium:
+		return "Medium"
+	case TFC_High:
+		return "High"
+	default:
+		return "TFC??"
+	}
+}
+
+// Type returned by DetectFile and DetectReader, contains encoding and confidence level
+type TextAutoDetect struct {
+	Encoding   TextFileEncoding
+	Confidence TextFileConfidence
+	CodePage   string // For TFE_EightBit, IANA name of code page detected from the sniffed sample
+
+	DeclaredCharset string             // Charset declared in the sniffed sample, "" if none
+	Source          TextEncodingSource // Whether Encoding has been guessed or declared
+
+	Kind      FileKind // For TFE_NotText, format identified from magic number, FK_Unknown if not recognized
+	Container FileKind // With Decoder.Decompress, FK_Gzip, FK_Bzip2 or FK_Xz for compressed content, FK_Unknown otherwise
 }
 
 // Since it's named String(), a format %s in fmt.Printf() will automatically call this function
@@ -78,6 +155,22 @@ func (enc TextFileEncoding) String() string {
 		return "UTF16LEBOM"
 	case TFE_UTF16BEBOM:
 		return "UTF16BEBOM"
+	case TFE_UTF32LE:
+		return "UTF32LE"
+	case TFE_UTF32BE:
+		return "UTF32BE"
+	case TFE_UTF32LEBOM:
+		return "UTF32LEBOM"
+	case TFE_UTF32BEBOM:
+		return "UTF32BEBOM"
+	case TFE_ShiftJIS:
+		return "ShiftJIS"
+	case TFE_GB18030:
+		return "GB18030"
+	case TFE_Big5:
+		return "Big5"
+	case TFE_EUCKR:
+		return "EUCKR"
 	default:
 		return "TFE??"
 	}
@@ -88,31 +181,132 @@ var (
 	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
 	utf16LEBOM = []byte{0xFF, 0xFE}
 	utf16BEBOM = []byte{0xFE, 0xFF}
+	utf32LEBOM = []byte{0xFF, 0xFE, 0x00, 0x00}
+	utf32BEBOM = []byte{0x00, 0x00, 0xFE, 0xFF}
 )
 
-// Heuristics constants
+// Heuristics default values, see Decoder
 const (
 	minSizeForUTF16NoBOMCheck = 20
 	minASCIIPercentage        = 0.75
 )
 
+// ReadTextFile reads and decodes the whole file.
+// Files detected as TFE_NotText from their first SampleSize bytes (MILLE by default) are not read further.
 func ReadTextFile(file string) (TextAutoDecode, error) {
-	f, err := os.Open(file)
-	if err != nil {
-		return TextAutoDecode{}, err
+	return DefaultDecoder.ReadTextFile(file)
+}
+
+// ReadTextFileWithPolicy is ReadTextFile with a specific policy for invalid sequences of Unicode encodings
+func ReadTextFileWithPolicy(file string, policy InvalidPolicy) (TextAutoDecode, error) {
+	d := DefaultDecoder
+	d.InvalidPolicy = policy
+	return d.ReadTextFile(file)
+}
+
+// DecodeReader reads and decodes r until EOF, using the same heuristics as ReadTextFile.
+// If r content is detected as TFE_NotText from its first SampleSize bytes (MILLE by default), r is not read further.
+func DecodeReader(r io.Reader) (TextAutoDecode, error) {
+	return DefaultDecoder.DecodeReader(r)
+}
+
+// DecodeReaderWithPolicy is DecodeReader with a specific policy for invalid sequences of Unicode encodings
+func DecodeReaderWithPolicy(r io.Reader, policy InvalidPolicy) (TextAutoDecode, error) {
+	d := DefaultDecoder
+	d.InvalidPolicy = policy
+	return d.DecodeReader(r)
+}
+
+// DecodeBytes decodes buffer, using the same heuristics as ReadTextFile
+func DecodeBytes(buffer []byte) TextAutoDecode {
+	return DefaultDecoder.DecodeBytes(buffer)
+}
+
+// DecodeBytesWithPolicy is DecodeBytes with a specific policy for invalid sequences of Unicode encodings
+func DecodeBytesWithPolicy(buffer []byte, policy InvalidPolicy) TextAutoDecode {
+	d := DefaultDecoder
+	d.InvalidPolicy = policy
+	return d.DecodeBytes(buffer)
+}
+
+// decode_all decodes a content starting with head, calling read_all to get the whole content only if head is not
+// complete and is text
+func (d *Decoder) decode_all(head []byte, complete bool, read_all func() ([]byte, error)) (TextAutoDecode, error) {
+	policy := d.InvalidPolicy
+	tad := d.sniff(head, complete)
+	// With a lenient policy, content may still be decoded if it has a BOM or contains mostly valid UTF-8, unless its
+	// magic number identifies a binary format, so that a binary file is never read completely
+	if tad.Encoding == TFE_NotText {
+		if kind := file_kind(head); policy == IP_Strict || kind != FK_Unknown || bom_encoding(head) == TFE_NotText && !mostly_utf8(head) {
+			tad.Kind = kind
+			return tad, nil
+		}
+	}
+
+	buffer_full := head
+	if !complete {
+		var err error
+		if buffer_full, err = read_all(); err != nil {
+			return TextAutoDecode{}, err
+		}
+	}
+
+	strict := tad
+	if !complete && tad.Encoding != TFE_NotText {
+		strict = d.final_decode(buffer_full, tad)
+	}
+	tad = d.apply_policy(buffer_full, tad, strict, policy)
+	if tad.Encoding == TFE_NotText {
+		tad.Kind = file_kind(head)
 	}
-	defer f.Close()
+	return tad, nil
+}
+
+// DetectFile detects file encoding reading at most the first SampleSize bytes (MILLE by default)
+func DetectFile(file string) (TextAutoDetect, error) {
+	return DefaultDecoder.DetectFile(file)
+}
+
+// DetectReader detects encoding of r reading at most the first SampleSize bytes (MILLE by default)
+func DetectReader(r io.Reader) (TextAutoDetect, error) {
+	return DefaultDecoder.DetectReader(r)
+}
+
+// read_head reads the first SampleSize bytes of r, complete is true if there is nothing more to read
+func (d *Decoder) read_head(r io.Reader) (head []byte, complete bool, err error) {
+	buffer_1000 := make([]byte, d.sample_size())
+	// Not io.ReadFull, that can't distinguish end of content from an io.ErrUnexpectedEOF returned by r, such as a
+	// truncated compressed stream
+	n := 0
+	for n < len(buffer_1000) {
+		m, err := r.Read(buffer_1000[n:])
+		n += m
+		if err == io.EOF {
+			return buffer_1000[:n], true, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return buffer_1000, false, nil
+}
+
+// sniff determines encoding from the first bytes of a file, and returns decoded text of these bytes.
+// If complete is false, there is more to read, so the encoding is only a hint (except TFE_NotText), and decoded text
+// may be truncated.
+func (d *Decoder) sniff(buffer_1000 []byte, complete bool) TextAutoDecode {
+	return d.apply_declared(d.guess(buffer_1000, complete), buffer_1000, complete)
+}
+
+// guess determines encoding from the content of the first bytes of a file, ignoring declarations
+func (d *Decoder) guess(buffer_1000 []byte, complete bool) TextAutoDecode {
+	n := len(buffer_1000)
 
 	// Empty file?
-	buffer_1000 := make([]byte, MILLE)
-	n, err := f.Read(buffer_1000)
 	if n == 0 {
-		return TextAutoDecode{Text: "", Encoding: TFE_Empty}, nil
+		return TextAutoDecode{Text: "", Encoding: TFE_Empty}
 	}
 
-	buffer_full := make([]byte, 0)
-	is_buffer_full_read := false
-
 	// First we check presence of BOM. If present, then file type is determined,
 	// if further checks fail, no need to continue.
 	// We don't consider the case of a EightBit (Windows 1252) file that would begin with these three BOM,
@@ -120,149 +314,179 @@ func ReadTextFile(file string) (TextAutoDecode, error) {
 
 	// UTF-8 BOM?
 	// Since we have a BOM, no need to check for ASCII subset
-	//if n >= 3 && buffer_1000[0] == 0xEF && buffer_1000[1] == 0xBB && buffer_1000[2] == 0xBF {
 	if bytes.HasPrefix(buffer_1000, utf8BOM) {
-		s, ok := check_utf8(buffer_1000, n)
-
+		s, ok := d.check_utf8(buffer_1000[len(utf8BOM):], n-len(utf8BOM), complete)
 		if !ok {
-			return TextAutoDecode{Text: "", Encoding: TFE_NotText}, nil
+			return TextAutoDecode{Text: "", Encoding: TFE_NotText}
 		}
+		return TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: TFE_UTF8BOM}
+	}
 
-		if n < MILLE {
-			return TextAutoDecode{Text: s[3:], Encoding: TFE_UTF8BOM}, nil
+	// UTF-32 BOMs are checked before UTF-16 ones, since UTF-32 LE BOM starts with UTF-16 LE BOM
+	if bytes.HasPrefix(buffer_1000, utf32LEBOM) || bytes.HasPrefix(buffer_1000, utf32BEBOM) {
+		encoding := TFE_UTF32LEBOM
+		if buffer_1000[0] == 0 {
+			encoding = TFE_UTF32BEBOM
 		}
-
-		return final_read(&is_buffer_full_read, &buffer_full, f, TFE_UTF8BOM)
+		s, ok := d.check_utf32(buffer_1000, n, encoding)
+		if !ok {
+			return TextAutoDecode{Text: "", Encoding: TFE_NotText}
+		}
+		return TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: encoding}
 	}
 
 	// UTF-16 LE BOM? (Windows)
-	//if n >= 2 && buffer_1000[0] == 0xFF && buffer_1000[1] == 0xFE {
 	if bytes.HasPrefix(buffer_1000, utf16LEBOM) {
-		s, ok := check_utf16(buffer_1000, n, TFE_UTF16LEBOM)
+		s, ok := d.check_utf16(buffer_1000, n, complete, TFE_UTF16LEBOM)
 		if !ok {
-			return TextAutoDecode{Text: "", Encoding: TFE_NotText}, nil
-		}
-
-		if n < MILLE {
-			return TextAutoDecode{Text: s, Encoding: TFE_UTF16LEBOM}, nil
+			return TextAutoDecode{Text: "", Encoding: TFE_NotText}
 		}
-
-		return final_read(&is_buffer_full_read, &buffer_full, f, TFE_UTF16LEBOM)
+		return TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: TFE_UTF16LEBOM}
 	}
 
 	// UTF-16 BE BOM?
-	//if n >= 2 && buffer_1000[0] == 0xFE && buffer_1000[1] == 0xFF {
 	if bytes.HasPrefix(buffer_1000, utf16BEBOM) {
-		s, ok := check_utf16(buffer_1000, n, TFE_UTF16BEBOM)
+		s, ok := d.check_utf16(buffer_1000, n, complete, TFE_UTF16BEBOM)
 		if !ok {
-			return TextAutoDecode{Text: "", Encoding: TFE_NotText}, nil
-		}
-
-		if n < MILLE {
-			return TextAutoDecode{Text: s, Encoding: TFE_UTF16BEBOM}, nil
+			return TextAutoDecode{Text: "", Encoding: TFE_NotText}
 		}
-
-		return final_read(&is_buffer_full_read, &buffer_full, f, TFE_UTF16BEBOM)
+		return TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: TFE_UTF16BEBOM}
 	}
 
 	// Then check encodings without BOM
 
 	// UTF-8 without BOM?
 	// Note that if string is only ASCII text, then type is assumed ASCII instead of UTF-8
-	s, ok := check_utf8(buffer_1000, n)
+	// We skip checking UTF-16, since it's a match for UTF-8/ASCII on the sniffed sample
+	s, ok := d.check_utf8(buffer_1000, n, complete)
 	if ok {
-		if n < 1000 {
-			var e TextFileEncoding
-			if is_ascii_text(&s) {
-				e = TFE_ASCII
-			} else {
-				e = TFE_UTF8
-			}
-			return TextAutoDecode{Text: s, Encoding: e}, nil
-		} else {
-			// Special case, first 1000 bytes are ASCII so we got there, but after 1000 bytes, we get 8-bit
-			// characters so we can't return if we didn't recognize the whole file as UTF-8
-			tad, err := final_read(&is_buffer_full_read, &buffer_full, f, TFE_UTF8)
-			if err == nil {
-				if tad.Encoding != TFE_NotText {
-					return tad, err
-				}
-			}
+		if is_ascii_text(&s.text) {
+			return TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: TFE_ASCII}
 		}
-
-		// We skip checking UTF-16, since it's a match for UTF-8/ASCII on the furst 1000 chars
-		return final_read(&is_buffer_full_read, &buffer_full, f, TFE_EightBit)
+		return TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: TFE_UTF8}
 	}
 
-	// UTF-16 LE? (Windows)
+	// UTF-32 and UTF-16 LE? (Windows)
 	// Only files with more than 10 characters (20 bytes) are tested and checked for 75% ASCII, or many small binary non text-files will match
-	if n > minSizeForUTF16NoBOMCheck {
-		s, ok := check_utf16(buffer_1000, n, TFE_UTF16LE)
+	// UTF-32 is checked first, since a UTF-32 text is mostly null bytes that could be decoded as UTF-16 null chars
+	if n > d.min_size_for_no_bom_check() {
+		s, ok := d.check_utf32(buffer_1000, n, TFE_UTF32LE)
 		if ok {
-			if n < 1000 {
-				return TextAutoDecode{Text: s, Encoding: TFE_UTF16LE}, nil
-			}
+			return TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: TFE_UTF32LE}
+		}
 
-			return final_read(
-				&is_buffer_full_read,
-				&buffer_full,
-				f,
-				TFE_UTF16LE)
+		s, ok = d.check_utf32(buffer_1000, n, TFE_UTF32BE)
+		if ok {
+			return TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: TFE_UTF32BE}
+		}
+
+		s, ok = d.check_utf16(buffer_1000, n, complete, TFE_UTF16LE)
+		if ok {
+			return TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: TFE_UTF16LE}
 		}
 
 		// UTF-16 BE?
-		s, ok = check_utf16(buffer_1000, n, TFE_UTF16BE)
+		s, ok = d.check_utf16(buffer_1000, n, complete, TFE_UTF16BE)
 		if ok {
-			if n < 1000 {
-				return TextAutoDecode{Text: s, Encoding: TFE_UTF16BE}, nil
-			}
-			return final_read(
-				&is_buffer_full_read,
-				&buffer_full,
-				f,
-				TFE_UTF16BE)
+			return TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: TFE_UTF16BE}
 		}
 	}
 
+	// CJK multi-byte encoding?
+	// Checked before 8-bit since any byte sequence is valid 8-bit text, but multi-byte encodings have a structure
+	if encoding, s, ok := d.check_cjk(buffer_1000, n, complete); ok {
+		return TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: encoding}
+	}
+
 	// 8-bit?
-	s, ok = check_eightbit(buffer_1000, n)
+	s, codePage, ok := d.check_eightbit(buffer_1000, n)
 	if ok {
-		if n < 1000 {
-			return TextAutoDecode{Text: s, Encoding: TFE_EightBit}, nil
-		} else {
-			return final_read(
-				&is_buffer_full_read,
-				&buffer_full,
-				f,
-				TFE_EightBit)
-		}
+		return TextAutoDecode{Text: s.text, Lines: s.lines, Encoding: TFE_EightBit, CodePage: codePage}
 	}
 
 	// None of the encodings worked without error
-	return TextAutoDecode{Text: "??", Encoding: TFE_NotText}, nil
+	return TextAutoDecode{Text: "", Encoding: TFE_NotText}
+}
+
+// final_decode decodes the whole buffer using encoding found by sniff on the first SampleSize bytes
+func (d *Decoder) final_decode(buffer_full []byte, sniffed TextAutoDecode) TextAutoDecode {
+	encoding := sniffed.Encoding
+	codePage := ""
+	if sniffed.Source == TES_Declared {
+		codePage = sniffed.CodePage
+	}
+
+	var tad TextAutoDecode
+	if encoding != TFE_ASCII && encoding != TFE_UTF8 {
+		tad = d.final_read(buffer_full, encoding, codePage)
+	} else {
+		// Special case, first SampleSize bytes are ASCII or UTF-8 so we got there, but after them, we may get 8-bit
+		// characters so we can't return if we didn't recognize the whole file as UTF-8
+		tad = d.final_read(buffer_full, TFE_UTF8, "")
+		declared, declaredCodePage, _ := declared_encoding(sniffed.DeclaredCharset)
+		declaredLegacy := declared == TFE_EightBit || cjk_encoding(declared) != nil
+		switch {
+		case tad.Encoding != TFE_NotText:
+			// Non-ASCII UTF-8 text contradicts a declared 8-bit or CJK encoding
+			if sniffed.Source == TES_Declared && tad.Encoding == TFE_UTF8 && declaredLegacy {
+				sniffed.Source = TES_Conflict
+			}
+
+		case sniffed.Source == TES_Declared && declaredLegacy && d.final_agrees(buffer_full, declared, declaredCodePage, &tad):
+			// Non-ASCII bytes after the sniffed sample agree with declared encoding, tad is decoded with it
+
+		default:
+			// Declared UTF-8 or ASCII, or declared 8-bit or CJK contradicted by content
+			if sniffed.Source == TES_Declared {
+				sniffed.Source = TES_Conflict
+			}
+			// For instance source code with Japanese comments after the sniffed sample
+			if cjk, _, ok := d.check_cjk(buffer_full, len(buffer_full), true); ok {
+				tad = d.final_read(buffer_full, cjk, "")
+			} else {
+				tad = d.final_read(buffer_full, TFE_EightBit, "")
+			}
+		}
+	}
+
+	tad.DeclaredCharset, tad.Source = sniffed.DeclaredCharset, sniffed.Source
+	return tad
+}
+
+// final_agrees decodes the whole buffer with a declared 8-bit or CJK encoding into tad, and returns false if content
+// contradicts it (see declared_agrees)
+func (d *Decoder) final_agrees(buffer_full []byte, declared TextFileEncoding, codePage string, tad *TextAutoDecode) bool {
+	res := d.final_read(buffer_full, declared, codePage)
+	if res.Encoding == TFE_NotText || declared == TFE_EightBit && d.contains_binary_chars(&res.Text, true) {
+		return false
+	}
+	*tad = res
+	return true
 }
 
 // The 75% ASCII test is too restrictive, some valid UTF-8 files are rejected (ex: output of tree command)
 // So we only detect control characters that should not be present in a text file
 // Old text files may contain FF (Form Feed, 12) or VT (Vertical Tab, 11), but it's unlikely for common files
-func contains_binary_chars(s *string, also_check_block_c1 bool) bool {
+func (d *Decoder) contains_binary_chars(s *string, also_check_block_c1 bool) bool {
 	for _, c := range *s {
 		if c < 32 && (c != 9 && c != 10 && c != 13) {
 			return true
 		}
-		// If requested, no characters of C1 is accepted (for all encodings but 8-bit)
-		if also_check_block_c1 && c >= 128 && c < 160 {
+		// If requested, no characters of C1 is accepted (for all encodings but 8-bit), unless decoder allows them
+		if also_check_block_c1 && !d.AllowC1 && c >= 128 && c < 160 {
 			return true
 		}
 	}
 	return false
 }
 
-// Check that string s doesn't contain a null char and contains at least 75% of ASCII 32..127, CR, LF, TAB
-func is_75percent_ascii(s *string) bool {
+// Check that string s doesn't contain a null char and contains at least MinASCIIRatio (75% by default) of ASCII 32..127, CR, LF, TAB
+func (d *Decoder) is_mostly_ascii(s *string) bool {
 	acount := 0
 	l := len(*s)
+	chars := 0
 	for _, c := range *s {
+		chars++
 		// For 8-bit files, we only exclude non-comon elements of C0 block, and DEL (127) char
 		// Anything in [128..255] is accepted
 		if c == 127 || c < 32 && (c != 9 && c != 10 && c != 13) {
@@ -275,47 +499,53 @@ func is_75percent_ascii(s *string) bool {
 	if l < 10 {
 		return true
 	} else {
-		return float64(acount)/float64(l) >= minASCIIPercentage
+		// Ratio uses characters, not bytes, or 3-byte box-drawing characters would count 3 times
+		return float64(acount)/float64(chars) >= d.min_ascii_ratio()
 	}
 }
 
-func final_read(is_buffer_full_read *bool, buffer_full *[]byte, file *os.File, encoding TextFileEncoding) (TextAutoDecode, error) {
-	// If the whole file has not been read yet, then read it
-	if !*is_buffer_full_read {
-		// Rewind the file position to the beginning
-		_, _ = file.Seek(0, io.SeekStart)
-		temp_buffer_full, err := io.ReadAll(file)
-		if err != nil {
-			return TextAutoDecode{}, err
-		}
-		*buffer_full = temp_buffer_full
-		*is_buffer_full_read = true
-	}
-
-	text := ""
+// final_read decodes the whole buffer with encoding. For TFE_EightBit, codePage is used if not empty, otherwise the
+// most plausible code page is detected.
+func (d *Decoder) final_read(buffer_full []byte, encoding TextFileEncoding, codePage string) TextAutoDecode {
+	var text decodedText
 	switch encoding {
 	case TFE_UTF8, TFE_UTF8BOM:
-		if utf8.Valid(*buffer_full) {
-			if encoding == TFE_UTF8 {
-				text = string(*buffer_full)
-			} else {
-				text = string(*buffer_full)[3:]
-			}
-		} else {
-			return TextAutoDecode{Text: "", Encoding: TFE_NotText}, nil
+		start := 0
+		if encoding == TFE_UTF8BOM {
+			start = len(utf8BOM)
 		}
+		s, ok := utf8_decode(buffer_full[start:])
+		if !ok {
+			return TextAutoDecode{Text: "", Encoding: TFE_NotText}
+		}
+		text = s
 
 	case TFE_UTF16LE, TFE_UTF16LEBOM, TFE_UTF16BE, TFE_UTF16BEBOM:
-		s, ok := utf16_decode(*buffer_full, encoding)
+		s, ok := utf16_decode(buffer_full, encoding)
+		if !ok {
+			return TextAutoDecode{Text: "", Encoding: TFE_NotText}
+		}
+		text = s
+
+	case TFE_UTF32LE, TFE_UTF32LEBOM, TFE_UTF32BE, TFE_UTF32BEBOM:
+		s, ok := utf32_decode(buffer_full, encoding)
 		if !ok {
-			return TextAutoDecode{Text: "", Encoding: TFE_NotText}, nil
+			return TextAutoDecode{Text: "", Encoding: TFE_NotText}
 		}
 		text = s
 
 	case TFE_EightBit:
-		s, ok := eightbit_decode(*buffer_full)
+		s, cp, ok := d.eightbit_decode(buffer_full, codePage)
 		if !ok {
-			return TextAutoDecode{Text: "", Encoding: TFE_NotText}, nil
+			return TextAutoDecode{Text: "", Encoding: TFE_NotText}
+		}
+		text = s
+		codePage = cp
+
+	case TFE_ShiftJIS, TFE_GB18030, TFE_Big5, TFE_EUCKR:
+		s, ok := cjk_decode(buffer_full, cjk_encoding(encoding), true)
+		if !ok {
+			return TextAutoDecode{Text: "", Encoding: TFE_NotText}
 		}
 		text = s
 
@@ -326,51 +556,51 @@ func final_read(is_buffer_full_read *bool, buffer_full *[]byte, file *os.File, e
 	check_ascii := encoding == TFE_UTF8 // UTF8_BOM is never considered ASCII
 
 	// Without BOM, we add heuristics to be sure that what has been decoded makes sense
-	check_75percent_text := encoding == TFE_EightBit || encoding == TFE_UTF16BE || encoding == TFE_UTF16LE
+	check_75percent_text := encoding == TFE_EightBit || encoding == TFE_UTF16BE || encoding == TFE_UTF16LE || encoding == TFE_UTF32BE || encoding == TFE_UTF32LE
 
 	// Special heuristics to be sure it's a valid text files
-	if check_75percent_text && !is_75percent_ascii(&text) {
-		return TextAutoDecode{Text: "", Encoding: TFE_NotText}, nil
+	if check_75percent_text && !d.is_mostly_ascii(&text.text) {
+		return TextAutoDecode{Text: "", Encoding: TFE_NotText}
 	}
-	if encoding != TFE_EightBit && contains_binary_chars(&text, true) {
-		return TextAutoDecode{Text: "", Encoding: TFE_NotText}, nil
+	if encoding != TFE_EightBit && d.contains_binary_chars(&text.text, true) {
+		return TextAutoDecode{Text: "", Encoding: TFE_NotText}
 	}
 
 	e := encoding
 	if check_ascii {
-		if is_ascii_text(&text) {
+		if is_ascii_text(&text.text) {
 			e = TFE_ASCII
 		} else {
 			e = TFE_UTF8
 		}
 	}
 
-	return TextAutoDecode{Text: text, Encoding: e}, nil
+	return TextAutoDecode{Text: text.text, Lines: text.lines, Encoding: e, CodePage: codePage}
 }
 
-// check_utf8 checks if a small byte buffer of n bytes (max 1000) contains a valid UTF-8 string.
+// check_utf8 checks if a small byte buffer of n bytes (max SampleSize) contains a valid UTF-8 string.
 // If valid, it returns the string and true.
 // If not valid, it returns an empty string and false.
-// Note that if buffer len is exactly 1000, it's possible that the last UTF-8 character is truncated,
+// Note that if buffer is not complete, it's possible that the last UTF-8 character is truncated,
 // so we reduce the buffer to be safe. Anyway, in this case, we'll reread the whole file and do a global check
-func check_utf8(buffer_1000 []byte, n int) (string, bool) {
+func (d *Decoder) check_utf8(buffer_1000 []byte, n int, complete bool) (decodedText, bool) {
 	if buffer_1000 == nil || n < 0 {
 		panic("Internal error")
 	}
 
 	nsafe := n
-	if n == MILLE {
-		for nsafe = MILLE - 1; ; nsafe-- {
+	if !complete && n > 0 {
+		for nsafe = n - 1; ; nsafe-- {
 			// If buffer[nsafe] is a valid beginning for UTF-8 encoding, we can stop here
 			if buffer_1000[nsafe] < 128 || (buffer_1000[nsafe]&0b11100000) == 0b11000000 || (buffer_1000[nsafe]&0b11110000) == 0b11100000 || (buffer_1000[nsafe]&0b11111000) == 0b11110000 {
 				break
 			}
 			// If it's a continuation character, we can continue, but at most three continuation characters are valid
-			if (buffer_1000[nsafe]&0b11000000) == 0b10000000 && n >= MILLE-3 {
+			if (buffer_1000[nsafe]&0b11000000) == 0b10000000 && nsafe >= n-3 && nsafe > 0 {
 				continue
 			}
 			// Sorry, that's not valid UTF-8...
-			return "", false
+			return decodedText{}, false
 		}
 
 		// If last character is <128, it's not truncated and can be kept
@@ -381,38 +611,37 @@ func check_utf8(buffer_1000 []byte, n int) (string, bool) {
 
 	buffer_safe := buffer_1000[:nsafe]
 
-	// Use utf8.Valid to check if the byte slice is valid UTF-8
-	if utf8.Valid(buffer_safe) {
-		s := string(buffer_safe)
-		if contains_binary_chars(&s, true) {
-			return "", false
+	// utf8_decode checks that the byte slice is valid UTF-8
+	if s, ok := utf8_decode(buffer_safe); ok {
+		if d.contains_binary_chars(&s.text, true) {
+			return decodedText{}, false
 		}
 
-		return string(buffer_safe), true
+		return s, true
 	}
 
-	// If not valid, return an empty string and false
-	return "", false
+	// If not valid, return an empty text and false
+	return decodedText{}, false
 }
 
-func check_utf16(buffer_1000 []byte, n int, encoding TextFileEncoding) (string, bool) {
+func (d *Decoder) check_utf16(buffer_1000 []byte, n int, complete bool, encoding TextFileEncoding) (decodedText, bool) {
 	if buffer_1000 == nil || n < 0 {
 		panic("Internal error")
 	}
 
-	// We have to check whether we truncated reading in the middle of a surrogate sequence when reading 1000 bytes max.
-	// Lead surrogate is 0xD800-0xDBFF (and tail surrogate is 0xDC00-0xDFFF), if the byte at index 998 is 0xD8, then
-	// we cut a surrogate. Note that optional byte order header (0xFF, 0xFE) is two bytes long, so all UTF-16 words
-	// start at even index.
+	// We have to check whether we truncated reading in the middle of a surrogate sequence when reading at most
+	// SampleSize bytes. Lead surrogate is 0xD800-0xDBFF (and tail surrogate is 0xDC00-0xDFFF), if the last word (at
+	// index 998 for the default sample size MILLE) is 0xD8, then we cut a surrogate. Sample size is a multiple of 4, so
+	// it's even, and optional byte order header (0xFF, 0xFE) is two bytes long, so all UTF-16 words start at even index.
 	nsafe := n
 
-	if n == MILLE {
+	if !complete && n >= 4 {
 		off := 0
 		if encoding == TFE_UTF16BE || encoding == TFE_UTF16BEBOM {
 			off = 1
 		}
 
-		pa := 998
+		pa := n - 2
 		if buffer_1000[pa+off] >= 0xD8 && buffer_1000[pa+off] <= 0xDB {
 			pa -= 2
 		}
@@ -427,24 +656,24 @@ func check_utf16(buffer_1000 []byte, n int, encoding TextFileEncoding) (string,
 
 	// If there is no BOM, actually UTF-16 BE can be decoded as UTF-16 LE and also the reverse in most of cases.
 	// To be sure there is no confusion, add an extra heuristics to check that content is 75% ASCII
-	if (encoding == TFE_UTF16LE || encoding == TFE_UTF16BE) && !is_75percent_ascii(&s) {
-		return "", false
+	if (encoding == TFE_UTF16LE || encoding == TFE_UTF16BE) && !d.is_mostly_ascii(&s.text) {
+		return decodedText{}, false
 	}
 
-	if !contains_binary_chars(&s, true) {
+	if !d.contains_binary_chars(&s.text, true) {
 		return s, ok
 	}
-	return "", false
+	return decodedText{}, false
 }
 
-func utf16_decode(buffer []byte, encoding TextFileEncoding) (string, bool) {
+func utf16_decode(buffer []byte, encoding TextFileEncoding) (decodedText, bool) {
 	// Buffer len must be even for UTF-16
 	if len(buffer)&1 == 1 {
-		return "", false
+		return decodedText{}, false
 	}
 
 	if len(buffer) == 0 {
-		return "", encoding == TFE_UTF16LE || encoding == TFE_UTF16BE
+		return decodedText{}, encoding == TFE_UTF16LE || encoding == TFE_UTF16BE
 	}
 
 	off := 0
@@ -456,7 +685,7 @@ func utf16_decode(buffer []byte, encoding TextFileEncoding) (string, bool) {
 	if encoding == TFE_UTF16BEBOM || encoding == TFE_UTF16LEBOM {
 		// Check BOM
 		if len(buffer) < 2 || buffer[off] != 0xFF || buffer[1-off] != 0xFE {
-			return "", false
+			return decodedText{}, false
 		}
 		start = 2
 	}
@@ -472,7 +701,8 @@ func utf16_decode(buffer []byte, encoding TextFileEncoding) (string, bool) {
 		surrSelf = 0x10000
 	)
 
-	var buf []rune
+	var tb textBuilder
+	tb.grow(len(buffer) / 2)
 	for start < len(buffer) {
 		// Already checked that buffer length is an even number, so at this point, buffer[start+1] exists
 		r := (int(buffer[start+off]) + (int(buffer[start+1-off]) << 8))
@@ -483,43 +713,113 @@ func utf16_decode(buffer []byte, encoding TextFileEncoding) (string, bool) {
 			ar = rune(r)
 		case r >= surr1 && r < surr2: // High surrogate
 			if start+2 >= len(buffer) { // Because even length, start+2 is enough
-				return "", false
+				return decodedText{}, false
 			}
 			start += 2
 			r2 := (int(buffer[start+off]) + (int(buffer[start+1-off]) << 8))
+			if r2 < surr2 || r2 >= surr3 { // High surrogate not followed by a low surrogate
+				return decodedText{}, false
+			}
 			ar = rune((r-surr1)<<10 | (r2 - surr2) + surrSelf)
 		default: // Low surrogate not following a high surrogate
-			return "", false
+			return decodedText{}, false
 		}
-		buf = append(buf, ar)
+		tb.write_rune(ar)
 		start += 2
 	}
-	return string(buf), true
+	return tb.result(), true
 }
 
-func check_eightbit(buffer_1000 []byte, n int) (string, bool) {
-	buffer := buffer_1000[:n]
-	s, ok := eightbit_decode(buffer)
-	if ok && is_75percent_ascii(&s) {
-		return s, ok
+// check_utf32 checks if a small byte buffer of n bytes (max SampleSize) contains valid UTF-32 text.
+// Since sample size is a multiple of 4 (MILLE by default), an incomplete buffer never contains a truncated character.
+// Without BOM, the null-byte pattern of UTF-32 (mostly 3 null bytes out of 4 for Latin text) is checked using the 75%
+// ASCII heuristic, and with the check that all characters are valid code points (upper byte is always null).
+func (d *Decoder) check_utf32(buffer_1000 []byte, n int, encoding TextFileEncoding) (decodedText, bool) {
+	if buffer_1000 == nil || n < 0 {
+		panic("Internal error")
 	}
 
-	return "", false
+	s, ok := utf32_decode(buffer_1000[:n], encoding)
+	if !ok {
+		return decodedText{}, false
+	}
+
+	if (encoding == TFE_UTF32LE || encoding == TFE_UTF32BE) && !d.is_mostly_ascii(&s.text) {
+		return decodedText{}, false
+	}
+
+	if !d.contains_binary_chars(&s.text, true) {
+		return s, true
+	}
+	return decodedText{}, false
 }
 
-func eightbit_decode(buffer []byte) (string, bool) {
-	// Create a new decoder for Windows CP 1252
-	decoder := charmap.Windows1252.NewDecoder()
+func utf32_decode(buffer []byte, encoding TextFileEncoding) (decodedText, bool) {
+	// Buffer len must be a multiple of 4 for UTF-32
+	if len(buffer)%4 != 0 {
+		return decodedText{}, false
+	}
+
+	if len(buffer) == 0 {
+		return decodedText{}, encoding == TFE_UTF32LE || encoding == TFE_UTF32BE
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if encoding == TFE_UTF32BE || encoding == TFE_UTF32BEBOM {
+		order = binary.BigEndian
+	}
+
+	start := 0
+	if encoding == TFE_UTF32LEBOM || encoding == TFE_UTF32BEBOM {
+		// Check BOM
+		if order.Uint32(buffer) != 0xFEFF {
+			return decodedText{}, false
+		}
+		start = 4
+	}
 
-	// Use io.ReadAll with the decoder to convert the byte slice
-	// transform.NewReader creates a new reader that decodes the input
-	utf8Bytes, err := io.ReadAll(decoder.Reader(bytes.NewReader(buffer)))
-	if err != nil {
-		return "", false
+	var tb textBuilder
+	tb.grow(len(buffer) / 4)
+	for ; start < len(buffer); start += 4 {
+		r := order.Uint32(buffer[start:])
+		// Surrogates are not valid in UTF-32
+		if r > unicode.MaxRune || r >= 0xD800 && r < 0xE000 {
+			return decodedText{}, false
+		}
+		tb.write_rune(rune(r))
 	}
+	return tb.result(), true
+}
 
-	// Convert the UTF-8 byte slice to a Go string
-	return string(utf8Bytes), true
+func (d *Decoder) check_eightbit(buffer_1000 []byte, n int) (decodedText, string, bool) {
+	buffer := buffer_1000[:n]
+	s, codePage, ok := d.eightbit_decode(buffer, "")
+	if ok && d.is_mostly_ascii(&s.text) {
+		return s, codePage, ok
+	}
+
+	return decodedText{}, "", false
+}
+
+// eightbit_decode decodes buffer with code page cpName, or with the most plausible 8-bit code page if cpName is "",
+// and returns code page name
+func (d *Decoder) eightbit_decode(buffer []byte, cpName string) (decodedText, string, bool) {
+	var cp codePage
+	if cpName == "" {
+		cp = best_codepage(buffer, d.FallbackCodePage)
+	} else {
+		cp, _ = find_codepage(cpName)
+	}
+	var tb textBuilder
+	tb.grow(len(buffer))
+	for _, c := range buffer {
+		if c < utf8.RuneSelf {
+			tb.write_ascii(c)
+		} else {
+			tb.write_rune(cp.charmap.DecodeByte(c))
+		}
+	}
+	return tb.result(), cp.name, true
 }
 
 func is_ascii_text(s *string) bool {
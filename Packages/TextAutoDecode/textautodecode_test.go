@@ -3,12 +3,49 @@
 //
 // 2025-06-23	PV		First version
 // 2025-07-02 	PV 		External test project moved along package itself
+// 2026-10-19 	PV 		Tests for DetectReader and ReadTextFile with in-memory contents
+// 2026-10-19 	PV 		Tests for DecodeBytes and DecodeReader
+// 2026-10-19 	PV 		Tests for NewDecodingReader
+// 2026-10-19 	PV 		Tests for UTF-32
+// 2026-10-19 	PV 		Tests for 8-bit code pages
+// 2026-10-19 	PV 		Tests for CJK encodings
+// 2026-10-19 	PV 		Tests for declared charsets
+// 2026-10-19 	PV 		Tests for Encoder and WriteTextFile
+// 2026-10-19 	PV 		Tests for lines statistics
+// 2026-10-19 	PV 		Tests for invalid sequences policies
+// 2026-10-19 	PV 		Tests for Decoder heuristics and file kinds
+// 2026-10-19 	PV 		Tests for decompression
+// 2026-10-19 	PV 		Tests for SourceOffset
+// 2026-10-19 	PV 		Tests for DecodeFiles
+// 2026-10-19 	PV 		Tests for declared ISO-8859-1 contradicted by Windows 1252 characters
+// 2026-10-19 	PV 		Test that a binary format is not read completely with a lenient policy
+// 2026-10-19 	PV 		Test of CJK text larger than a decoding chunk
+// 2026-10-19 	PV 		DecodeFiles budget of compressed and empty files
 
 package TextAutoDecode
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+
+	"github.com/ulikunitz/xz"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
 )
 
 func TestDecode(t *testing.T) {	
@@ -54,4 +91,994 @@ func test(t *testing.T, filename string, expected TextFileEncoding) {
 		l := min(len(tad.Text), 80)
 		t.Errorf("Decoding %s, got \n%s\ninstead of\n%s\n", filename, tad.Text[:l], beginning)
 	}
-}
\ No newline at end of file
+}
+
+// countingReader counts bytes read from an underlying reader
+type countingReader struct {
+	r     io.Reader
+	count int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.count += n
+	return n, err
+}
+
+func TestDetectReader(t *testing.T) {
+	ascii := strings.Repeat("Hello world\r\n", 100)
+	tests := []struct {
+		name       string
+		content    []byte
+		encoding   TextFileEncoding
+		confidence TextFileConfidence
+	}{
+		{"Empty", []byte{}, TFE_Empty, TFC_High},
+		{"Short ASCII", []byte("Hello world\n"), TFE_ASCII, TFC_High},
+		{"Long ASCII", []byte(ascii), TFE_ASCII, TFC_Low},
+		{"Short UTF-8", []byte("juliette sophie brigitte géraldine\n"), TFE_UTF8, TFC_High},
+		{"Long UTF-8", []byte("géraldine\n" + ascii), TFE_UTF8, TFC_Medium},
+		{"UTF-8 BOM", append([]byte{0xEF, 0xBB, 0xBF}, ascii...), TFE_UTF8BOM, TFC_High},
+		{"UTF-16 LE BOM", append([]byte{0xFF, 0xFE}, []byte("H\x00e\x00l\x00l\x00o\x00")...), TFE_UTF16LEBOM, TFC_High},
+		{"UTF-16 LE", []byte(strings.Repeat("H\x00e\x00l\x00l\x00o\x00 \x00", 200)), TFE_UTF16LE, TFC_Medium},
+		{"UTF-32 LE BOM", utf32enc(ascii, false, true), TFE_UTF32LEBOM, TFC_High},
+		{"UTF-32 BE", utf32enc(ascii, true, false), TFE_UTF32BE, TFC_Medium},
+		{"Long 8-bit", []byte(strings.Repeat("G\xe9raldine ", 100)), TFE_EightBit, TFC_Low},
+		{"Binary", append([]byte{0x7F, 'E', 'L', 'F', 0, 0, 1, 2}, make([]byte, 1<<20)...), TFE_NotText, TFC_High},
+	}
+
+	for _, tt := range tests {
+		cr := &countingReader{r: bytes.NewReader(tt.content)}
+		det, err := DetectReader(cr)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if det.Encoding != tt.encoding || det.Confidence != tt.confidence {
+			t.Errorf("%s: got %s/%s, want %s/%s", tt.name, det.Encoding, det.Confidence, tt.encoding, tt.confidence)
+		}
+		if cr.count > MILLE {
+			t.Errorf("%s: %d bytes read, want at most %d", tt.name, cr.count, MILLE)
+		}
+	}
+}
+
+func TestReadTextFileLateEightBit(t *testing.T) {
+	// ASCII for more than 1000 bytes, then a Windows-1252 é
+	path := filepath.Join(t.TempDir(), "late8bit.txt")
+	content := strings.Repeat("juliette sophie brigitte\r\n", 50) + "g\xe9raldine\r\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	det, err := DetectFile(path)
+	if err != nil || det.Encoding != TFE_ASCII || det.Confidence != TFC_Low {
+		t.Errorf("DetectFile: got %s/%s (err %v), want ASCII/Low", det.Encoding, det.Confidence, err)
+	}
+
+	tad, err := ReadTextFile(path)
+	if err != nil || tad.Encoding != TFE_EightBit {
+		t.Fatalf("ReadTextFile: got %s (err %v), want EightBit", tad.Encoding, err)
+	}
+	if !strings.HasSuffix(tad.Text, "géraldine\r\n") {
+		t.Errorf("ReadTextFile: wrong decoded text ending %q", tad.Text[len(tad.Text)-20:])
+	}
+}
+
+// utf16le encodes s in UTF-16 LE, with an optional BOM
+func utf16le(s string, bom bool) []byte {
+	var b []byte
+	if bom {
+		b = append(b, 0xFF, 0xFE)
+	}
+	for _, w := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(w), byte(w>>8))
+	}
+	return b
+}
+
+// utf32enc encodes s in UTF-32 LE or BE, with an optional BOM
+func utf32enc(s string, bigEndian, bom bool) []byte {
+	var b []byte
+	if bom {
+		s = "\uFEFF" + s
+	}
+	for _, r := range s {
+		if bigEndian {
+			b = append(b, byte(r>>24), byte(r>>16), byte(r>>8), byte(r))
+		} else {
+			b = append(b, byte(r), byte(r>>8), byte(r>>16), byte(r>>24))
+		}
+	}
+	return b
+}
+
+func TestDecodeBytes(t *testing.T) {
+	long := strings.Repeat("juliette sophie brigitte géraldine 🎉\r\n", 50)
+	tests := []struct {
+		name     string
+		content  []byte
+		encoding TextFileEncoding
+		text     string
+	}{
+		{"Empty", nil, TFE_Empty, ""},
+		{"ASCII", []byte("Hello\n"), TFE_ASCII, "Hello\n"},
+		{"UTF-8", []byte(long), TFE_UTF8, long},
+		{"UTF-8 BOM", append([]byte{0xEF, 0xBB, 0xBF}, long...), TFE_UTF8BOM, long},
+		{"UTF-16 LE BOM", utf16le(long, true), TFE_UTF16LEBOM, long},
+		{"UTF-16 LE", utf16le(long, false), TFE_UTF16LE, long},
+		{"UTF-32 LE BOM", utf32enc(long, false, true), TFE_UTF32LEBOM, long},
+		{"UTF-32 BE BOM", utf32enc(long, true, true), TFE_UTF32BEBOM, long},
+		{"UTF-32 LE", utf32enc(long, false, false), TFE_UTF32LE, long},
+		{"UTF-32 BE", utf32enc(long, true, false), TFE_UTF32BE, long},
+		{"Short UTF-32 LE BOM", utf32enc("Hi", false, true), TFE_UTF32LEBOM, "Hi"},
+		{"8-bit", []byte(strings.Repeat("g\xe9raldine\n", 200)), TFE_EightBit, strings.Repeat("géraldine\n", 200)},
+		{"Binary", []byte{0, 1, 2, 3, 4, 5}, TFE_NotText, ""},
+	}
+
+	for _, tt := range tests {
+		tad := DecodeBytes(tt.content)
+		if tad.Encoding != tt.encoding || tad.Text != tt.text {
+			t.Errorf("DecodeBytes %s: got %s, want %s, or decoded text is wrong", tt.name, tad.Encoding, tt.encoding)
+		}
+
+		tad, err := DecodeReader(bytes.NewReader(tt.content))
+		if err != nil || tad.Encoding != tt.encoding || tad.Text != tt.text {
+			t.Errorf("DecodeReader %s: got %s (err %v), want %s, or decoded text is wrong", tt.name, tad.Encoding, err, tt.encoding)
+		}
+	}
+}
+
+func TestDecodingReader(t *testing.T) {
+	long := strings.Repeat("juliette sophie brigitte géraldine 🎉\r\n", 100)
+	late8bit := strings.Repeat("juliette sophie brigitte\r\n", 50) + "g\xe9raldine\r\n"
+	tests := []struct {
+		name          string
+		content       []byte
+		encoding      TextFileEncoding
+		finalEncoding TextFileEncoding
+	}{
+		{"Empty", nil, TFE_Empty, TFE_Empty},
+		{"ASCII", []byte("Hello\n"), TFE_ASCII, TFE_ASCII},
+		{"UTF-8", []byte(long), TFE_UTF8, TFE_UTF8},
+		{"UTF-8 BOM", append([]byte{0xEF, 0xBB, 0xBF}, long...), TFE_UTF8BOM, TFE_UTF8BOM},
+		{"ASCII then UTF-8", []byte(late8bit[:1300] + "géraldine"), TFE_ASCII, TFE_UTF8},
+		{"ASCII then 8-bit", []byte(late8bit), TFE_ASCII, TFE_EightBit},
+		{"UTF-16 LE BOM", utf16le(long, true), TFE_UTF16LEBOM, TFE_UTF16LEBOM},
+		{"UTF-16 LE", utf16le(long, false), TFE_UTF16LE, TFE_UTF16LE},
+		{"UTF-32 BE BOM", utf32enc(long, true, true), TFE_UTF32BEBOM, TFE_UTF32BEBOM},
+		{"UTF-32 LE", utf32enc(long, false, false), TFE_UTF32LE, TFE_UTF32LE},
+		{"8-bit", []byte(strings.Repeat("g\xe9raldine\n", 200)), TFE_EightBit, TFE_EightBit},
+	}
+
+	for _, tt := range tests {
+		// One byte at a time to check BOM, UTF-8 sequences and UTF-16 surrogates split between reads
+		rd, encoding, err := NewDecodingReader(iotest.OneByteReader(bytes.NewReader(tt.content)))
+		if err != nil || encoding != tt.encoding {
+			t.Errorf("%s: got %s (err %v), want %s", tt.name, encoding, err, tt.encoding)
+			continue
+		}
+		decoded, err := io.ReadAll(rd)
+		if err != nil {
+			t.Errorf("%s: read error %v", tt.name, err)
+			continue
+		}
+		if want := DecodeBytes(tt.content).Text; string(decoded) != want {
+			t.Errorf("%s: decoded text differs from DecodeBytes, got %d bytes, want %d", tt.name, len(decoded), len(want))
+		}
+		if rd.Encoding() != tt.finalEncoding {
+			t.Errorf("%s: final encoding %s, want %s", tt.name, rd.Encoding(), tt.finalEncoding)
+		}
+	}
+
+	rd, encoding, err := NewDecodingReader(bytes.NewReader([]byte{0, 1, 2, 3, 4, 5}))
+	if rd != nil || encoding != TFE_NotText || err != nil {
+		t.Errorf("Binary: got %v, %s, %v, want nil, NotText, nil", rd, encoding, err)
+	}
+}
+
+func TestCodePages(t *testing.T) {
+	french := "Géraldine est allée à l'école, où elle a mangé une crème brûlée. Ça c'est très français!\r\n"
+	tests := []struct {
+		text     string
+		charmap  *charmap.Charmap
+		codePage string
+	}{
+		{french, charmap.Windows1252, "windows-1252"},
+		{french, charmap.CodePage850, "IBM850"},
+		{french, charmap.Macintosh, "macintosh"},
+		{"Le cœur a ses raisons, 10 €\r\n" + french, charmap.ISO8859_15, "ISO-8859-15"},
+		{"╞═╡ Main menu of the program\r\n│ 1. Start the program\r\n│ 2. Exit\r\n╘═╛\r\n", charmap.CodePage437, "IBM437"},
+	}
+
+	for _, tt := range tests {
+		encoded, err := tt.charmap.NewEncoder().Bytes([]byte(tt.text))
+		if err != nil {
+			t.Fatalf("Can't encode %q with %s: %v", tt.text, tt.codePage, err)
+		}
+
+		// Short text in a single sniff, then long text decoded completely
+		for _, content := range [][]byte{encoded, bytes.Repeat(encoded, 20)} {
+			tad := DecodeBytes(content)
+			if tad.Encoding != TFE_EightBit || tad.CodePage != tt.codePage {
+				t.Errorf("%s: got %s/%s, want EightBit/%s", tt.codePage, tad.Encoding, tad.CodePage, tt.codePage)
+				continue
+			}
+			if !strings.HasPrefix(tad.Text, tt.text) {
+				t.Errorf("%s: wrong decoded text %q", tt.codePage, tad.Text[:min(len(tad.Text), 40)])
+			}
+		}
+	}
+}
+
+func TestCJK(t *testing.T) {
+	tests := []struct {
+		text     string
+		encoder  encoding.Encoding
+		expected TextFileEncoding
+	}{
+		{"日本語のテキストです。これは私の本です。今日はいい天気ですね。\r\n", japanese.ShiftJIS, TFE_ShiftJIS},
+		{"这是中文文本。我们的国家很大，人民在一起生活和工作。\r\n", simplifiedchinese.GB18030, TFE_GB18030},
+		{"這是中文文本。我們的國家很大，人民在一起生活和工作。\r\n", traditionalchinese.Big5, TFE_Big5},
+		{"한국어 텍스트입니다. 이것은 나의 책입니다. 오늘은 날씨가 좋습니다.\r\n", korean.EUCKR, TFE_EUCKR},
+	}
+
+	for _, tt := range tests {
+		encoded, err := tt.encoder.NewEncoder().Bytes([]byte(tt.text))
+		if err != nil {
+			t.Fatalf("Can't encode %q with %s: %v", tt.text, tt.expected, err)
+		}
+
+		// Short text in a single sniff, then long text where first 1000 bytes end in the middle of a character, and
+		// finally Japanese/Chinese/Korean comments in a source file after 1000 ASCII bytes
+		long := bytes.Repeat(encoded, 50)
+		late := append([]byte(strings.Repeat("// ASCII header\r\n", 70)), long...)
+		for _, content := range [][]byte{encoded, long, late} {
+			tad := DecodeBytes(content)
+			if tad.Encoding != tt.expected {
+				t.Errorf("%s: got encoding %s", tt.expected, tad.Encoding)
+				continue
+			}
+			if !strings.Contains(tad.Text, tt.text) {
+				t.Errorf("%s: wrong decoded text %q", tt.expected, tad.Text[:min(len(tad.Text), 40)])
+			}
+		}
+
+		// Text larger than a decoding chunk
+		if tad := DecodeBytes(bytes.Repeat(encoded, 2000)); tad.Text != strings.Repeat(tt.text, 2000) || tad.Lines.CRLF != 2000 {
+			t.Errorf("%s: wrong decoded text of %d bytes, %d CRLF", tt.expected, len(tad.Text), tad.Lines.CRLF)
+		}
+
+		det, err := DetectReader(bytes.NewReader(long))
+		if err != nil || det.Encoding != tt.expected || det.Confidence != TFC_Medium {
+			t.Errorf("%s: DetectReader got %s/%s, %v", tt.expected, det.Encoding, det.Confidence, err)
+		}
+
+		rd, enc, err := NewDecodingReader(iotest.OneByteReader(bytes.NewReader(late)))
+		if err != nil || enc != TFE_ASCII {
+			t.Fatalf("%s: NewDecodingReader got %s, %v", tt.expected, enc, err)
+		}
+		decoded, err := io.ReadAll(rd)
+		if err != nil || !strings.HasSuffix(string(decoded), tt.text) || rd.Encoding() != tt.expected {
+			t.Errorf("%s: streaming got %s, %v", tt.expected, rd.Encoding(), err)
+		}
+	}
+
+	// Invalid Shift_JIS after a valid beginning is not text
+	encoded, _ := japanese.ShiftJIS.NewEncoder().Bytes([]byte(strings.Repeat("これは私の本です。", 100)))
+	if tad := DecodeBytes(append(encoded, 0x81, 0x20)); tad.Encoding != TFE_NotText {
+		t.Errorf("Invalid Shift_JIS: got %s, want NotText", tad.Encoding)
+	}
+}
+
+func TestDeclaredCharset(t *testing.T) {
+	french := "Géraldine est allée à l'école, où elle a mangé une crème brûlée.\r\n"
+	latin1, _ := charmap.ISO8859_1.NewEncoder().String(french)
+	cp1252, _ := charmap.Windows1252.NewEncoder().String(french)
+	cp850, _ := charmap.CodePage850.NewEncoder().String(french)
+	sjis, _ := japanese.ShiftJIS.NewEncoder().String("これは私の本です。今日はいい天気ですね。")
+	asciiHeader := strings.Repeat("<!-- ASCII header -->\r\n", 50)
+
+	tests := []struct {
+		name     string
+		content  []byte
+		encoding TextFileEncoding
+		codePage string
+		declared string
+		source   TextEncodingSource
+	}{
+		{"No declaration", []byte(cp1252), TFE_EightBit, "windows-1252", "", TES_Guessed},
+		{"XML prolog", []byte(`<?xml version="1.0" encoding="ISO-8859-1"?>` + "\r\n<p>" + latin1 + "</p>"), TFE_EightBit, "ISO-8859-1", "ISO-8859-1", TES_Declared},
+		{"Python cookie", []byte("#!/usr/bin/python\n# -*- coding: cp1252 -*-\n# " + cp1252), TFE_EightBit, "windows-1252", "cp1252", TES_Declared},
+		{"Vim modeline", []byte("# vim: set fileencoding=latin-1 :\n# " + latin1), TFE_EightBit, "ISO-8859-1", "latin-1", TES_Declared},
+		{"HTML meta", []byte(`<html><head><meta charset="utf-8"></head><body>` + french), TFE_UTF8, "", "utf-8", TES_Declared},
+		{"HTML http-equiv", []byte(`<meta http-equiv="Content-Type" content="text/html; charset=windows-1252">` + french), TFE_UTF8, "", "windows-1252", TES_Conflict},
+		{"CSS charset", []byte(`@charset "Shift_JIS";` + "\n/* " + sjis + " */"), TFE_ShiftJIS, "", "Shift_JIS", TES_Declared},
+		{"Unknown charset", []byte(`<?xml version="1.0" encoding="no-such-charset"?><p/>`), TFE_ASCII, "", "no-such-charset", TES_Conflict},
+		{"UTF-16 declared", utf16le(`<?xml version="1.0" encoding="UTF-16"?><p>`+french+"</p>", true), TFE_UTF16LEBOM, "", "UTF-16", TES_Declared},
+		{"Late 8-bit", []byte(`<?xml version="1.0" encoding="IBM850"?>` + "\r\n" + asciiHeader + cp850), TFE_EightBit, "IBM850", "IBM850", TES_Declared},
+		{"Late UTF-8", []byte(`<?xml version="1.0" encoding="IBM850"?>` + "\r\n" + asciiHeader + french), TFE_UTF8, "", "IBM850", TES_Conflict},
+		// Curly quotes and € of Windows 1252 are C1 control characters in ISO-8859-1
+		{"C1 in ISO-8859-1", []byte(`<?xml version="1.0" encoding="ISO-8859-1"?>` + "\r\n<p>\x93" + latin1 + "\x94 \x80</p>"), TFE_EightBit, "windows-1252", "ISO-8859-1", TES_Conflict},
+		{"Late C1 in ISO-8859-1", []byte(`<?xml version="1.0" encoding="ISO-8859-1"?>` + "\r\n" + asciiHeader + "<p>\x93" + latin1 + "\x94 \x80</p>"), TFE_EightBit, "windows-1252", "ISO-8859-1", TES_Conflict},
+	}
+
+	for _, tt := range tests {
+		tad := DecodeBytes(tt.content)
+		if tad.Encoding != tt.encoding || tad.CodePage != tt.codePage || tad.DeclaredCharset != tt.declared || tad.Source != tt.source {
+			t.Errorf("%s: got %s/%s/%s/%s, want %s/%s/%s/%s", tt.name, tad.Encoding, tad.CodePage, tad.DeclaredCharset, tad.Source,
+				tt.encoding, tt.codePage, tt.declared, tt.source)
+		}
+	}
+
+	// Streaming uses declared code page when non-ASCII bytes are found after the first 1000 bytes
+	rd, _, err := NewDecodingReader(strings.NewReader(`<?xml version="1.0" encoding="IBM850"?>` + "\r\n" + asciiHeader + cp850))
+	if err != nil {
+		t.Fatalf("NewDecodingReader: %v", err)
+	}
+	decoded, err := io.ReadAll(rd)
+	if err != nil || !strings.HasSuffix(string(decoded), french) || rd.CodePage() != "IBM850" {
+		t.Errorf("Streaming: got %s/%s, %v", rd.Encoding(), rd.CodePage(), err)
+	}
+
+	tad := DecodeBytes([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?>` + "\r\n<p>\x93" + latin1 + "\x94 \x80</p>"))
+	if !strings.Contains(tad.Text, "<p>“Géraldine") || !strings.Contains(tad.Text, "” €</p>") {
+		t.Errorf("C1 in ISO-8859-1: got %q", tad.Text)
+	}
+
+	// Streaming ignores a declared code page contradicted by content
+	rd, _, err = NewDecodingReader(strings.NewReader(`<?xml version="1.0" encoding="ISO-8859-1"?>` + "\r\n" + asciiHeader + "<p>\x93" + latin1 + "\x94 \x80</p>"))
+	if err != nil {
+		t.Fatalf("NewDecodingReader: %v", err)
+	}
+	decoded, err = io.ReadAll(rd)
+	if err != nil || !strings.HasSuffix(string(decoded), "” €</p>") || rd.CodePage() != "windows-1252" {
+		t.Errorf("Streaming C1 in ISO-8859-1: got %s/%s, %v", rd.Encoding(), rd.CodePage(), err)
+	}
+
+	det, err := DetectReader(strings.NewReader(`<?xml version="1.0" encoding="ISO-8859-1"?>` + latin1 + strings.Repeat(latin1, 20)))
+	if err != nil || det.Encoding != TFE_EightBit || det.Source != TES_Declared || det.Confidence != TFC_High {
+		t.Errorf("DetectReader: got %s/%s/%s, %v", det.Encoding, det.Source, det.Confidence, err)
+	}
+}
+
+func TestEncoderRoundTrip(t *testing.T) {
+	french := "Géraldine est allée à l'école, où elle a mangé une crème brûlée.\r\nÇa c'est très français!\n"
+	encode := func(e encoding.Encoding, s string) []byte {
+		b, err := e.NewEncoder().Bytes([]byte(s))
+		if err != nil {
+			t.Fatalf("Can't encode %q: %v", s, err)
+		}
+		return b
+	}
+
+	contents := map[string][]byte{
+		"ASCII":        []byte("Hello\r\nWorld\n\tIndented\rOld Mac\r\n"),
+		"UTF-8":        []byte(french + "♫ 山 🐗\n"),
+		"UTF-8 BOM":    append([]byte{0xEF, 0xBB, 0xBF}, french...),
+		"UTF-16 LE":    utf16le(french, false),
+		"UTF-16 LEBOM": utf16le(french+"🐗", true),
+		"UTF-32 BE":    utf32enc(french, true, false),
+		"UTF-32 LEBOM": utf32enc(french+"🐗", false, true),
+		"Windows 1252": encode(charmap.Windows1252, "Le cœur a ses raisons, 10 €. "+french),
+		"IBM850":       encode(charmap.CodePage850, french),
+		"Shift_JIS":    encode(japanese.ShiftJIS, "日本語のテキストです。これは私の本です。今日はいい天気ですね。\r\n"),
+		"GB18030":      encode(simplifiedchinese.GB18030, "这是中文文本。我们的国家很大，人民在一起生活和工作。\r\n"),
+		"Big5":         encode(traditionalchinese.Big5, "這是中文文本。我們的國家很大，人民在一起生活和工作。\r\n"),
+		"EUC-KR":       encode(korean.EUCKR, "한국어 텍스트입니다. 이것은 나의 책입니다. 오늘은 날씨가 좋습니다.\r\n"),
+	}
+
+	for name, content := range contents {
+		for _, c := range [][]byte{content, bytes.Repeat(content, 40)} {
+			tad := DecodeBytes(c)
+			if tad.Encoding == TFE_NotText {
+				t.Fatalf("%s: not recognized as text", name)
+			}
+
+			var buf bytes.Buffer
+			e, err := NewEncoder(&buf, tad.Encoding, tad.CodePage, EOL_Keep)
+			if err != nil {
+				t.Fatalf("%s: NewEncoder: %v", name, err)
+			}
+			// Write by small chunks to split characters and EOL between calls
+			for text := tad.Text; text != ""; {
+				n := min(len(text), 7)
+				if _, err := io.WriteString(e, text[:n]); err != nil {
+					t.Fatalf("%s: Write: %v", name, err)
+				}
+				text = text[n:]
+			}
+			if err := e.Close(); err != nil {
+				t.Fatalf("%s: Close: %v", name, err)
+			}
+			if !bytes.Equal(buf.Bytes(), c) {
+				t.Errorf("%s (%s %s): round trip is not identical", name, tad.Encoding, tad.CodePage)
+			}
+		}
+	}
+}
+
+func TestEncoderEOL(t *testing.T) {
+	text := "a\r\nb\nc\rd\r\r\ne\r"
+	tests := []struct {
+		eol      EOLStyle
+		expected string
+	}{
+		{EOL_Keep, text},
+		{EOL_CRLF, "a\r\nb\r\nc\r\nd\r\n\r\ne\r\n"},
+		{EOL_LF, "a\nb\nc\nd\n\ne\n"},
+		{EOL_CR, "a\rb\rc\rd\r\re\r"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		e, _ := NewEncoder(&buf, TFE_ASCII, "", tt.eol)
+		// One byte at a time, so CR and LF are written in different calls
+		for i := range len(text) {
+			if _, err := e.Write([]byte{text[i]}); err != nil {
+				t.Fatalf("%s: Write: %v", tt.eol, err)
+			}
+		}
+		e.Close()
+		if buf.String() != tt.expected {
+			t.Errorf("%s: got %q, want %q", tt.eol, buf.String(), tt.expected)
+		}
+	}
+
+	// EOL in UTF-16 is converted before encoding
+	var buf bytes.Buffer
+	e, _ := NewEncoder(&buf, TFE_UTF16LEBOM, "", EOL_CRLF)
+	io.WriteString(e, "é\nà")
+	e.Close()
+	if !bytes.Equal(buf.Bytes(), utf16le("é\r\nà", true)) {
+		t.Errorf("UTF-16 LE CRLF: got % X", buf.Bytes())
+	}
+}
+
+func TestEncoderUnrepresentable(t *testing.T) {
+	var buf bytes.Buffer
+	e, err := NewEncoder(&buf, TFE_EightBit, "IBM850", EOL_Keep)
+	if err != nil {
+		t.Fatalf("NewEncoder: %v", err)
+	}
+	n, err := io.WriteString(e, "Prix: 10 € TTC")
+	var ue *UnrepresentableError
+	if !errors.As(err, &ue) || ue.Char != '€' || ue.Offset != 9 || ue.CodePage != "IBM850" {
+		t.Fatalf("Expected UnrepresentableError for € at offset 9, got %v", err)
+	}
+	if n != 9 || buf.String() != "Prix: 10 " {
+		t.Errorf("Expected 9 bytes written before error, got %d %q", n, buf.String())
+	}
+
+	if _, err := NewEncoder(&buf, TFE_EightBit, "no-such-codepage", EOL_Keep); err == nil {
+		t.Error("Expected an error for an unknown code page")
+	}
+	if _, err := NewEncoder(&buf, TFE_NotText, "", EOL_Keep); err == nil {
+		t.Error("Expected an error for TFE_NotText")
+	}
+
+	// File is not created if text can't be encoded
+	path := filepath.Join(t.TempDir(), "ascii.txt")
+	if err := WriteTextFile(path, "Café", TFE_ASCII, "", EOL_Keep); err == nil {
+		t.Error("Expected an error writing é in ASCII")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("File should not exist, got %v", err)
+	}
+}
+
+func TestWriteTextFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.txt")
+	if err := WriteTextFile(path, "Géraldine\nest allée à l'école\n", TFE_EightBit, "ISO-8859-15", EOL_CRLF); err != nil {
+		t.Fatalf("WriteTextFile: %v", err)
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != "G\xe9raldine\r\nest all\xe9e \xe0 l'\xe9cole\r\n" {
+		t.Errorf("Wrong content % X", content)
+	}
+
+	if err := WriteTextFile(path, "", TFE_UTF8BOM, "", EOL_Keep); err != nil {
+		t.Fatalf("WriteTextFile: %v", err)
+	}
+	tad, err := ReadTextFile(path)
+	if err != nil || tad.Encoding != TFE_UTF8BOM || tad.Text != "" {
+		t.Errorf("Empty UTF-8 BOM file: got %s %q, %v", tad.Encoding, tad.Text, err)
+	}
+}
+
+func TestLineStats(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected LineStats
+	}{
+		{"", LineStats{}},
+		{"One line", LineStats{LineCount: 1, LongestLine: 8}},
+		{"Unix\nfile\n", LineStats{LF: 2, FinalNewline: true, LineCount: 2, LongestLine: 4}},
+		{"Windows\r\nfile", LineStats{CRLF: 1, LineCount: 2, LongestLine: 7}},
+		{"Mac\rfile\r", LineStats{CR: 2, FinalNewline: true, LineCount: 2, LongestLine: 4}},
+		{"Mixed\r\n\nécole 🐗\r", LineStats{CRLF: 1, LF: 1, CR: 1, Mixed: true, FinalNewline: true, LineCount: 3, LongestLine: 7}},
+		{"\n\n\n", LineStats{LF: 3, FinalNewline: true, LineCount: 3}},
+		// Longer than 1000 bytes, so it's decoded in two steps
+		{strings.Repeat("Line\r\n", 300) + "Last line", LineStats{CRLF: 300, LineCount: 301, LongestLine: 9}},
+		{strings.Repeat("Crème brûlée\n", 300), LineStats{LF: 300, FinalNewline: true, LineCount: 300, LongestLine: 12}},
+	}
+
+	for _, tt := range tests {
+		tad := DecodeBytes([]byte(tt.text))
+		if tad.Lines != tt.expected {
+			t.Errorf("%q: got %+v, want %+v", tt.text[:min(len(tt.text), 20)], tad.Lines, tt.expected)
+		}
+
+		if tt.text == "" {
+			continue
+		}
+		rd, _, err := NewDecodingReader(iotest.OneByteReader(strings.NewReader(tt.text)))
+		if err != nil {
+			t.Fatalf("NewDecodingReader: %v", err)
+		}
+		io.ReadAll(rd)
+		if rd.Lines() != tt.expected {
+			t.Errorf("%q streaming: got %+v, want %+v", tt.text[:min(len(tt.text), 20)], rd.Lines(), tt.expected)
+		}
+	}
+
+	// Statistics are computed by each decoder, including lenient ones
+	text := strings.Repeat("Géraldine est allée\r\nà l'école 日本\n", 40) + "Dernière ligne sans EOL"
+	expected := LineStats{CRLF: 40, LF: 40, Mixed: true, LineCount: 81, LongestLine: 23}
+	for _, enc := range []TextFileEncoding{TFE_UTF8BOM, TFE_UTF16LEBOM, TFE_UTF16BE, TFE_UTF32BEBOM} {
+		var buf bytes.Buffer
+		e, _ := NewEncoder(&buf, enc, "", EOL_Keep)
+		e.Write([]byte(text))
+		e.Close()
+		for _, policy := range []InvalidPolicy{IP_Strict, IP_Replace} {
+			content := buf.Bytes()
+			if policy == IP_Replace && enc == TFE_UTF8BOM {
+				content = append(content, 0xFF)
+				expected.LongestLine++
+			}
+			if tad := DecodeBytesWithPolicy(content, policy); tad.Lines != expected {
+				t.Errorf("%s %s: got %+v, want %+v", enc, policy, tad.Lines, expected)
+			}
+		}
+		expected.LongestLine = 23
+	}
+	if tad := DecodeBytes([]byte(strings.Repeat("Cr\xe8me br\xfbl\xe9e\r\n", 100))); tad.Encoding != TFE_EightBit ||
+		tad.Lines != (LineStats{CRLF: 100, FinalNewline: true, LineCount: 100, LongestLine: 12}) {
+		t.Errorf("8-bit: got %s %+v", tad.Encoding, tad.Lines)
+	}
+	sjis, _ := japanese.ShiftJIS.NewEncoder().String(strings.Repeat("日本語のテキストです。\n", 100))
+	if tad := DecodeBytes([]byte(sjis)); tad.Encoding != TFE_ShiftJIS ||
+		tad.Lines != (LineStats{LF: 100, FinalNewline: true, LineCount: 100, LongestLine: 11}) {
+		t.Errorf("Shift_JIS: got %s %+v", tad.Encoding, tad.Lines)
+	}
+
+	if style := (LineStats{CRLF: 2, LF: 5, CR: 1}).Style(); style != EOL_LF {
+		t.Errorf("Style: got %s, want LF", style)
+	}
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	french := "Géraldine est allée à l'école, où elle a mangé une crème brûlée.\n"
+	long := strings.Repeat(french, 20)
+	latin1, _ := charmap.ISO8859_1.NewEncoder().String(french)
+	surrogate := utf16le(french, true)
+	surrogate = append(surrogate[:10:10], append([]byte{0x00, 0xD8}, surrogate[10:]...)...) // Unpaired high surrogate
+
+	tests := []struct {
+		name    string
+		content []byte
+		strict  TextFileEncoding
+		lenient TextFileEncoding
+		offsets []int64
+	}{
+		{"Late invalid byte", []byte(long + "\xff" + french), TFE_EightBit, TFE_UTF8, []int64{int64(len(long))}},
+		{"Early invalid bytes", []byte("Ca\xe7a, " + french + "\xe0 " + french), TFE_EightBit, TFE_UTF8, []int64{2, int64(len(french)) + 6}},
+		{"UTF-8 BOM", []byte("\xef\xbb\xbf" + french + "\xff"), TFE_NotText, TFE_UTF8BOM, []int64{int64(len(french)) + 3}},
+		{"UTF-16 surrogate", surrogate, TFE_NotText, TFE_UTF16LEBOM, []int64{10}},
+		{"Real 8-bit", []byte(strings.Repeat("ASCII line\n", 100) + latin1), TFE_EightBit, TFE_EightBit, nil},
+		{"Binary", append([]byte(french+"\xff"), 0, 1, 2, 3), TFE_NotText, TFE_NotText, nil},
+	}
+
+	for _, tt := range tests {
+		if tad := DecodeBytes(tt.content); tad.Encoding != tt.strict {
+			t.Errorf("%s strict: got %s, want %s", tt.name, tad.Encoding, tt.strict)
+		}
+
+		tad := DecodeBytesWithPolicy(tt.content, IP_Replace)
+		if tad.Encoding != tt.lenient || tad.InvalidCount != len(tt.offsets) || tad.InvalidOffsets != nil {
+			t.Errorf("%s replace: got %s with %d invalid %v", tt.name, tad.Encoding, tad.InvalidCount, tad.InvalidOffsets)
+		}
+
+		tad, err := DecodeReaderWithPolicy(bytes.NewReader(tt.content), IP_Report)
+		if err != nil || tad.Encoding != tt.lenient || tad.InvalidCount != len(tt.offsets) || fmt.Sprint(tad.InvalidOffsets) != fmt.Sprint(tt.offsets) {
+			t.Errorf("%s report: got %s with %d invalid %v, want %s %v", tt.name, tad.Encoding, tad.InvalidCount, tad.InvalidOffsets, tt.lenient, tt.offsets)
+		}
+		if len(tt.offsets) > 0 && strings.Count(tad.Text, "\uFFFD") != len(tt.offsets) {
+			t.Errorf("%s report: expected %d U+FFFD in text", tt.name, len(tt.offsets))
+		}
+	}
+
+	// A binary format identified by its magic number is not read further, even if it contains mostly valid UTF-8
+	r := bytes.NewReader([]byte("PK\x03\x04\x00" + long))
+	if tad, err := DecodeReaderWithPolicy(r, IP_Report); err != nil || tad.Encoding != TFE_NotText || tad.Kind != FK_Zip || r.Len() == 0 {
+		t.Errorf("ZIP report: got %s %s with %d bytes left to read, want NotText ZIP not read completely", tad.Encoding, tad.Kind, r.Len())
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	ascii := strings.Repeat("ASCII line\n", 20)
+	latin1, _ := charmap.ISO8859_1.NewEncoder().String(strings.Repeat("éé ab\n", 10))
+	short := utf16le("Bonjour!", false)
+
+	tests := []struct {
+		name    string
+		decoder Decoder
+		content []byte
+		want    TextFileEncoding
+	}{
+		{"UTF-8 after small sample", Decoder{SampleSize: 100}, []byte(ascii + "été\n"), TFE_UTF8},
+		{"Accented 8-bit", Decoder{}, []byte(latin1), TFE_NotText},
+		{"Accented 8-bit with lower ratio", Decoder{MinASCIIRatio: 0.5}, []byte(latin1), TFE_EightBit},
+		{"C1 in UTF-8", Decoder{}, []byte("Line\u0085Next line\n"), TFE_EightBit},
+		{"C1 in UTF-8 allowed", Decoder{AllowC1: true}, []byte("Line\u0085Next line\n"), TFE_UTF8},
+		{"Short UTF-16", Decoder{}, short, TFE_NotText},
+		{"Short UTF-16 always checked", Decoder{MinSizeForNoBOMCheck: -1}, short, TFE_UTF16LE},
+	}
+
+	for _, tt := range tests {
+		if tad := tt.decoder.DecodeBytes(tt.content); tad.Encoding != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, tad.Encoding, tt.want)
+		}
+		tad, err := tt.decoder.DecodeReader(bytes.NewReader(tt.content))
+		if err != nil || tad.Encoding != tt.want {
+			t.Errorf("%s reader: got %s (%v), want %s", tt.name, tad.Encoding, err, tt.want)
+		}
+	}
+
+	// Sample size is rounded to a multiple of 4 so UTF-16 and UTF-32 samples are never truncated in a character
+	d := Decoder{SampleSize: 102}
+	content := utf16le(strings.Repeat("Texte accentué\n", 20), true)
+	if tad := d.DecodeBytes(content); tad.Encoding != TFE_UTF16LEBOM {
+		t.Errorf("UTF-16 with sample size 102: got %s", tad.Encoding)
+	}
+	// Binary content after the sample is only found when decoding
+	binary := append([]byte(ascii), 0, 1, 2)
+	if ad, _ := d.DetectReader(bytes.NewReader(binary)); ad.Encoding != TFE_ASCII || ad.Confidence != TFC_Low {
+		t.Errorf("DetectReader with small sample: got %s %s", ad.Encoding, ad.Confidence)
+	}
+	if ad, _ := DetectReader(bytes.NewReader(binary)); ad.Encoding != TFE_NotText {
+		t.Errorf("DetectReader with default sample: got %s", ad.Encoding)
+	}
+
+	// Preferred code page when scores are equal, ° is the same character in these code pages
+	degree := []byte("Temperature: 20\xb0 today\n")
+	for _, cp := range []string{"", "ISO-8859-15", "ISO-8859-1"} {
+		want := cp
+		if cp == "" {
+			want = "windows-1252"
+		}
+		d := Decoder{FallbackCodePage: cp}
+		if tad := d.DecodeBytes(degree); tad.CodePage != want || tad.Text != "Temperature: 20° today\n" {
+			t.Errorf("Fallback code page %q: got %s %q", cp, tad.CodePage, tad.Text)
+		}
+	}
+	if _, err := (&Decoder{FallbackCodePage: "no-such-cp"}).DecodeReader(bytes.NewReader(degree)); err == nil {
+		t.Error("Unknown fallback code page: expected an error")
+	}
+
+	// Package functions use DefaultDecoder
+	saved := DefaultDecoder
+	DefaultDecoder.AllowC1 = true
+	if tad := DecodeBytes([]byte("Line\u0085Next line\n")); tad.Encoding != TFE_UTF8 {
+		t.Errorf("DefaultDecoder.AllowC1: got %s", tad.Encoding)
+	}
+	DefaultDecoder = saved
+}
+
+func TestFileKind(t *testing.T) {
+	zipHeader := func(name string) []byte {
+		h := make([]byte, 30)
+		copy(h, "PK\x03\x04")
+		h[26] = byte(len(name))
+		return append(append(h, name...), 0, 0, 0, 0xff)
+	}
+	tar := make([]byte, 512)
+	copy(tar, "file.txt")
+	copy(tar[257:], "ustar\x0000")
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    FileKind
+	}{
+		{"ELF", []byte("\x7fELF\x02\x01\x01\x00\x00\x00"), FK_ELF},
+		{"PE", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00"), FK_PE},
+		{"Java class", []byte("\xca\xfe\xba\xbe\x00\x00\x00\x34\x00\x1d"), FK_JavaClass},
+		{"Mach-O universal", []byte("\xca\xfe\xba\xbe\x00\x00\x00\x02\x01\x00"), FK_MachO},
+		{"ZIP", zipHeader("readme.txt"), FK_Zip},
+		{"OOXML", zipHeader("[Content_Types].xml"), FK_OOXML},
+		{"PDF", []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n\x00\x01"), FK_PDF},
+		{"PNG", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"), FK_PNG},
+		{"JPEG", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), FK_JPEG},
+		{"gzip", []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\x03"), FK_Gzip},
+		{"xz", []byte("\xfd7zXZ\x00\x00\x04\xe6\xd6"), FK_Xz},
+		{"SQLite", []byte("SQLite format 3\x00\x10\x00\x01\x01"), FK_SQLite},
+		{"WebP", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), FK_WebP},
+		{"tar", tar, FK_Tar},
+		{"Unknown", []byte{0xCA, 0xFE, 0xDE, 0xAD, 0xBE, 0xEF}, FK_Unknown},
+	}
+
+	for _, tt := range tests {
+		tad := DecodeBytes(tt.content)
+		if tad.Encoding != TFE_NotText || tad.Kind != tt.want {
+			t.Errorf("%s: got %s %s, want NotText %s", tt.name, tad.Encoding, tad.Kind, tt.want)
+		}
+		ad, err := DetectReader(bytes.NewReader(tt.content))
+		if err != nil || ad.Kind != tt.want {
+			t.Errorf("%s DetectReader: got %s (%v), want %s", tt.name, ad.Kind, err, tt.want)
+		}
+	}
+
+	// Kind is only set for non-text content
+	if tad := DecodeBytes([]byte("BZh is a text file\n")); tad.Encoding != TFE_ASCII || tad.Kind != FK_Unknown {
+		t.Errorf("Text starting with a magic number: got %s %s", tad.Encoding, tad.Kind)
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	text := "2026-10-19 12:00:00 Démarrage du service\r\n2026-10-19 12:00:01 Prêt\r\n"
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(text))
+	zw.Close()
+	// Concatenated members, as produced by cat a.gz b.gz
+	zw = gzip.NewWriter(&gz)
+	zw.Write([]byte(text))
+	zw.Close()
+
+	var xzBuf bytes.Buffer
+	xw, _ := xz.NewWriter(&xzBuf)
+	xw.Write([]byte(text))
+	xw.Close()
+
+	// compress/bzip2 has no writer, "Hello, compressed world!\nSecond line\n" compressed by bzip2 -9
+	bz2 := []byte("BZh91AY&SY\xca\x5b\x96\xf0\x00\x00\x03\xd7\x80\x00\x10\x60\x04\x00\x40\x08\x00\x0e\x27\xd8" +
+		"\x80\x20\x00\x22\x23\x26\x09\xa3\x4c\x85\x34\xc8\xc4\xc4\xc4\x62\xd3\x63\xa6\x08\x9d\xcf\x07\x75\x14" +
+		"\x79\x55\x27\x09\x19\x0b\xab\x48\x67\xf8\xbb\x92\x29\xc2\x84\x86\x52\xdc\xb7\x80")
+
+	tests := []struct {
+		name      string
+		content   []byte
+		encoding  TextFileEncoding
+		container FileKind
+		text      string
+	}{
+		{"gzip", gz.Bytes(), TFE_UTF8, FK_Gzip, text + text},
+		{"xz", xzBuf.Bytes(), TFE_UTF8, FK_Xz, text},
+		{"bzip2", bz2, TFE_ASCII, FK_Bzip2, "Hello, compressed world!\nSecond line\n"},
+		{"Not compressed", []byte("BZh is not always bzip2\n"), TFE_ASCII, FK_Unknown, "BZh is not always bzip2\n"},
+	}
+
+	d := Decoder{Decompress: true}
+	for _, tt := range tests {
+		tad, err := d.DecodeReader(bytes.NewReader(tt.content))
+		if err != nil || tad.Encoding != tt.encoding || tad.Container != tt.container || tad.Text != tt.text {
+			t.Errorf("%s: got %s %s %q (%v), want %s %s", tt.name, tad.Encoding, tad.Container, tad.Text, err, tt.encoding, tt.container)
+		}
+		if tad := d.DecodeBytes(tt.content); tad.Encoding != tt.encoding || tad.Container != tt.container {
+			t.Errorf("%s DecodeBytes: got %s %s", tt.name, tad.Encoding, tad.Container)
+		}
+		ad, err := d.DetectReader(bytes.NewReader(tt.content))
+		if err != nil || ad.Encoding != tt.encoding || ad.Container != tt.container {
+			t.Errorf("%s DetectReader: got %s %s (%v)", tt.name, ad.Encoding, ad.Container, err)
+		}
+		rd, _, err := d.NewDecodingReader(bytes.NewReader(tt.content))
+		if err != nil || rd == nil {
+			t.Errorf("%s NewDecodingReader: %v", tt.name, err)
+			continue
+		}
+		if b, _ := io.ReadAll(rd); string(b) != tt.text || rd.Container() != tt.container {
+			t.Errorf("%s NewDecodingReader: got %s %q", tt.name, rd.Container(), b)
+		}
+	}
+
+	// Without Decompress, compressed content is not text
+	if tad := DecodeBytes(gz.Bytes()); tad.Encoding != TFE_NotText || tad.Kind != FK_Gzip || tad.Container != FK_Unknown {
+		t.Errorf("gzip without Decompress: got %s %s %s", tad.Encoding, tad.Kind, tad.Container)
+	}
+
+	// Truncated compressed content
+	truncated := gz.Bytes()[:gz.Len()/3]
+	if _, err := d.DecodeReader(bytes.NewReader(truncated)); err == nil {
+		t.Error("Truncated gzip: expected an error")
+	}
+	if tad := d.DecodeBytes(truncated); tad.Encoding != TFE_NotText || tad.Kind != FK_Gzip {
+		t.Errorf("Truncated gzip DecodeBytes: got %s %s", tad.Encoding, tad.Kind)
+	}
+}
+
+func TestSourceOffset(t *testing.T) {
+	french := strings.Repeat("Ligne 1: Café crème\r\nLigne 2: où est l'été?\n", 200)
+	unicode := strings.Repeat("Line α: smile 😀, café\r\n", 300)
+	japanese := strings.Repeat("日本語のテキストです。Line 2\n", 300)
+
+	tests := []struct {
+		text     string
+		encoding TextFileEncoding
+		codePage string
+	}{
+		{unicode, TFE_UTF8, ""},
+		{unicode, TFE_UTF8BOM, ""},
+		{unicode, TFE_UTF16LEBOM, ""},
+		{unicode, TFE_UTF16BE, ""},
+		{unicode, TFE_UTF32BEBOM, ""},
+		{french, TFE_EightBit, "windows-1252"},
+		{japanese, TFE_ShiftJIS, ""},
+	}
+
+	for _, tt := range tests {
+		// Expected offsets are computed encoding text one character at a time
+		var source bytes.Buffer
+		e, err := NewEncoder(&source, tt.encoding, tt.codePage, EOL_Keep)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.encoding, err)
+		}
+		e.Write(nil) // BOM
+		var expected []int64
+		for _, r := range tt.text {
+			expected = append(expected, int64(source.Len()))
+			e.Write([]byte(string(r)))
+		}
+		e.Close()
+
+		tad := DecodeBytes(source.Bytes())
+		if tad.Encoding != tt.encoding || tad.Text != tt.text {
+			t.Errorf("%s: decoded as %s", tt.encoding, tad.Encoding)
+			continue
+		}
+		i := 0
+		for pos, r := range tt.text {
+			if got := tad.SourceOffset(pos); got != expected[i] {
+				t.Errorf("%s: SourceOffset(%d) = %d, want %d", tt.encoding, pos, got, expected[i])
+				break
+			}
+			// Offset inside a character
+			if size := len(string(r)); size > 1 {
+				if got := tad.SourceOffset(pos + size - 1); got != expected[i] {
+					t.Errorf("%s: SourceOffset(%d) inside character = %d, want %d", tt.encoding, pos+size-1, got, expected[i])
+					break
+				}
+			}
+			i++
+		}
+		if got := tad.SourceOffset(len(tad.Text) + 10); got != int64(source.Len()) {
+			t.Errorf("%s: SourceOffset after end = %d, want %d", tt.encoding, got, source.Len())
+		}
+	}
+
+	// Invalid bytes replaced by U+FFFD count for one byte
+	content := []byte("Crème brûlée \xff\xfe et café\n")
+	tad := DecodeBytesWithPolicy(content, IP_Replace)
+	pos := strings.Index(tad.Text, "café")
+	if got := tad.SourceOffset(pos); got != int64(bytes.Index(content, []byte("café"))) {
+		t.Errorf("Lenient UTF-8: SourceOffset(%d) = %d", pos, got)
+	}
+}
+
+func TestDecodeFiles(t *testing.T) {
+	// Files of various sizes and encodings, and a missing file
+	dir := t.TempDir()
+	var paths, texts []string
+	for i := range 20 {
+		text := strings.Repeat(fmt.Sprintf("Fichier %d, café crème\n", i), 1+i*50)
+		path := filepath.Join(dir, fmt.Sprintf("file%02d.txt", i))
+		content := []byte(text)
+		if i%3 == 1 {
+			content = utf16le(text, true)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+		texts = append(texts, text)
+	}
+	paths = append(paths, filepath.Join(dir, "missing.txt"))
+	texts = append(texts, "")
+
+	feed := func(ctx context.Context) <-chan string {
+		ch := make(chan string)
+		go func() {
+			defer close(ch)
+			for _, path := range paths {
+				select {
+				case ch <- path:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return ch
+	}
+
+	// Budget smaller than largest files, which are then read alone
+	for _, ordered := range []bool{true, false} {
+		d := Decoder{MaxInFlightBytes: 10000, Ordered: ordered}
+		seen := map[string]bool{}
+		i := 0
+		for res := range d.DecodeFiles(context.Background(), feed(context.Background()), 4) {
+			if ordered && res.Path != paths[i] {
+				t.Errorf("Ordered: result %d is %s, want %s", i, res.Path, paths[i])
+			}
+			k := slices.Index(paths, res.Path)
+			if k < 0 || seen[res.Path] {
+				t.Errorf("Ordered=%v: unexpected result %s", ordered, res.Path)
+				continue
+			}
+			seen[res.Path] = true
+			if k == len(paths)-1 {
+				if !errors.Is(res.Err, os.ErrNotExist) {
+					t.Errorf("Ordered=%v: missing file error %v", ordered, res.Err)
+				}
+			} else if res.Err != nil || res.Text != texts[k] {
+				t.Errorf("Ordered=%v: %s decoded as %s (err %v)", ordered, res.Path, res.Encoding, res.Err)
+			}
+			i++
+		}
+		if i != len(paths) {
+			t.Errorf("Ordered=%v: got %d results, want %d", ordered, i, len(paths))
+		}
+	}
+
+	// A highly compressible gzip file is charged the whole budget, not its compressed size, and so is a file of size 0,
+	// which may be a FIFO or a /proc entry
+	large := strings.Repeat("Ligne de journal très répétitive\n", 100000)
+	gz := filepath.Join(dir, "large.log.gz")
+	var zb bytes.Buffer
+	zw := gzip.NewWriter(&zb)
+	zw.Write([]byte(large))
+	zw.Close()
+	if err := os.WriteFile(gz, zb.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.txt")
+	_ = os.WriteFile(empty, nil, 0644)
+	d := Decoder{MaxInFlightBytes: 64 * 1024, Decompress: true}
+	for _, tt := range []struct {
+		path string
+		cost int64
+	}{{gz, d.MaxInFlightBytes}, {empty, d.MaxInFlightBytes}, {paths[1], 4 * int64(len(utf16le(texts[1], true)))}, {paths[len(paths)-1], 0}} {
+		if cost := d.file_cost(tt.path, d.MaxInFlightBytes); cost != tt.cost {
+			t.Errorf("file_cost(%s): got %d, want %d", filepath.Base(tt.path), cost, tt.cost)
+		}
+	}
+	d.Ordered = true
+	batch := make(chan string, 3)
+	batch <- paths[0]
+	batch <- gz
+	batch <- paths[2]
+	close(batch)
+	var got []string
+	for res := range d.DecodeFiles(context.Background(), batch, 4) {
+		if res.Err != nil {
+			t.Errorf("Compressed batch: %s error %v", res.Path, res.Err)
+		}
+		got = append(got, res.Text)
+	}
+	if !slices.Equal(got, []string{texts[0], large, texts[2]}) {
+		t.Errorf("Compressed batch: got %d wrong results", len(got))
+	}
+
+	// Cancellation closes results channel without reading all paths
+	ctx, cancel := context.WithCancel(context.Background())
+	results := DecodeFiles(ctx, feed(ctx), 2)
+	<-results
+	cancel()
+	n := 1
+	for range results {
+		n++
+	}
+	if n == len(paths) {
+		t.Errorf("Cancel: all %d files were delivered", n)
+	}
+}
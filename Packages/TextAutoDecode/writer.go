@@ -0,0 +1,292 @@
+// writer.go
+// Encoding of UTF-8 text to any supported encoding, with optional BOM and EOL conversion, to write back or convert
+// text files. Text read with ReadTextFile and written with the same Encoding and CodePage, and EOL_Keep, is
+// byte-identical to the original file (for CJK encodings, this holds except for the few characters that have
+// several encodings, such as NEC and IBM extensions of Shift_JIS).
+//
+// 2026-10-19	PV		First version
+
+package TextAutoDecode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+// EOLStyle controls end of lines conversion when encoding text
+type EOLStyle int
+
+const (
+	EOL_Keep EOLStyle = iota // No conversion
+	EOL_CRLF                 // Windows
+	EOL_LF                   // Unix
+	EOL_CR                   // Old Mac
+)
+
+func (eol EOLStyle) String() string {
+	switch eol {
+	case EOL_Keep:
+		return "Keep"
+	case EOL_CRLF:
+		return "CRLF"
+	case EOL_LF:
+		return "LF"
+	case EOL_CR:
+		return "CR"
+	default:
+		return "EOL??"
+	}
+}
+
+// UnrepresentableError is returned when text contains a character that target encoding can't represent
+type UnrepresentableError struct {
+	Char     rune             // Character that can't be encoded
+	Offset   int64            // Offset in bytes of the character in text written to Encoder, before EOL conversion
+	Encoding TextFileEncoding // Target encoding
+	CodePage string           // Target code page for TFE_EightBit
+}
+
+func (e *UnrepresentableError) Error() string {
+	target := e.Encoding.String()
+	if e.CodePage != "" {
+		target = e.CodePage
+	}
+	return fmt.Sprintf("character %q (U+%04X) at offset %d can't be represented in %s", e.Char, e.Char, e.Offset, target)
+}
+
+// Encoder is an io.Writer accepting UTF-8 text, and writing it to an underlying writer in a chosen encoding.
+// BOM is written by first call to Write, or by Close if nothing has been written.
+// Close must be called at the end to flush an incomplete UTF-8 sequence, it doesn't close the underlying writer.
+type Encoder struct {
+	w        io.Writer
+	encoding TextFileEncoding
+	codePage string
+	charmap  *charmap.Charmap      // Only for TFE_EightBit
+	cjk      transform.Transformer // Only for CJK encodings
+	eol      []byte                // nil for EOL_Keep
+	bom      []byte                // nil when written, or if encoding has no BOM
+
+	pending   []byte // Incomplete UTF-8 sequence at the end of previous Write
+	pendingCR bool   // Previous character was CR, so a LF following it is part of the same EOL
+	offset    int64  // Number of text bytes processed
+	out       []byte // Reused output buffer
+}
+
+// NewEncoder returns an Encoder writing text to w in encoding. For TFE_EightBit, codePage is the IANA name of the
+// code page such as "windows-1252" or "IBM850" (as returned in CodePage field by ReadTextFile), "" for Windows 1252.
+// TFE_Empty is written as UTF-8 without BOM.
+func NewEncoder(w io.Writer, encoding TextFileEncoding, codePage string, eol EOLStyle) (*Encoder, error) {
+	e := &Encoder{w: w, encoding: encoding}
+
+	switch encoding {
+	case TFE_Empty, TFE_ASCII, TFE_UTF8, TFE_UTF16LE, TFE_UTF16BE, TFE_UTF32LE, TFE_UTF32BE:
+	case TFE_UTF8BOM:
+		e.bom = utf8BOM
+	case TFE_UTF16LEBOM:
+		e.bom = utf16LEBOM
+	case TFE_UTF16BEBOM:
+		e.bom = utf16BEBOM
+	case TFE_UTF32LEBOM:
+		e.bom = utf32LEBOM
+	case TFE_UTF32BEBOM:
+		e.bom = utf32BEBOM
+	case TFE_EightBit:
+		if codePage == "" {
+			codePage = codePages[0].name
+		}
+		cp, ok := find_codepage(codePage)
+		if !ok {
+			return nil, fmt.Errorf("unknown code page %s", codePage)
+		}
+		e.charmap, e.codePage = cp.charmap, cp.name
+	case TFE_ShiftJIS, TFE_GB18030, TFE_Big5, TFE_EUCKR:
+		e.cjk = cjk_encoding(encoding).NewEncoder()
+	default:
+		return nil, fmt.Errorf("can't encode text as %s", encoding)
+	}
+
+	switch eol {
+	case EOL_Keep:
+	case EOL_CRLF:
+		e.eol = []byte("\r\n")
+	case EOL_LF:
+		e.eol = []byte("\n")
+	case EOL_CR:
+		e.eol = []byte("\r")
+	default:
+		return nil, fmt.Errorf("invalid EOL style %d", eol)
+	}
+
+	return e, nil
+}
+
+// Write encodes UTF-8 text p. In case of an UnrepresentableError, text before the character has been written, and n
+// is the number of bytes of p before the character.
+func (e *Encoder) Write(p []byte) (n int, err error) {
+	if err := e.write_bom(); err != nil {
+		return 0, err
+	}
+
+	data := p
+	if len(e.pending) > 0 {
+		data = append(e.pending, p...)
+	}
+	pendingLen := len(e.pending)
+	e.pending = nil
+
+	e.out = e.out[:0]
+	i := 0
+	for i < len(data) {
+		// Keep an incomplete sequence at the end for next Write, a rune may be split between two calls
+		if !utf8.FullRune(data[i:]) {
+			e.pending = append([]byte(nil), data[i:]...)
+			i = len(data)
+			break
+		}
+		_, size := utf8.DecodeRune(data[i:])
+		if err = e.append_char(data[i : i+size]); err != nil {
+			break
+		}
+		e.offset += int64(size)
+		i += size
+	}
+
+	if _, werr := e.w.Write(e.out); werr != nil {
+		return 0, werr
+	}
+	return max(i-pendingLen, 0), err
+}
+
+// Close writes BOM if nothing has been written, and encodes an incomplete UTF-8 sequence left by the last Write.
+// The underlying writer is not closed.
+func (e *Encoder) Close() error {
+	if err := e.write_bom(); err != nil {
+		return err
+	}
+	if len(e.pending) == 0 {
+		return nil
+	}
+
+	e.out = e.out[:0]
+	for _, b := range e.pending {
+		if err := e.append_char([]byte{b}); err != nil {
+			return err
+		}
+		e.offset++
+	}
+	e.pending = nil
+	_, err := e.w.Write(e.out)
+	return err
+}
+
+func (e *Encoder) write_bom() error {
+	if e.bom == nil {
+		return nil
+	}
+	bom := e.bom
+	e.bom = nil
+	_, err := e.w.Write(bom)
+	return err
+}
+
+// append_char appends encoded character c (a valid or invalid UTF-8 sequence) to e.out, converting EOL
+func (e *Encoder) append_char(c []byte) error {
+	if e.eol != nil && (c[0] == '\r' || c[0] == '\n') {
+		lfAfterCR := c[0] == '\n' && e.pendingCR
+		e.pendingCR = c[0] == '\r'
+		if lfAfterCR {
+			return nil
+		}
+		for _, b := range e.eol {
+			if err := e.append_rune(rune(b), []byte{b}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	e.pendingCR = false
+
+	r, _ := utf8.DecodeRune(c)
+	return e.append_rune(r, c)
+}
+
+// append_rune appends encoded rune r to e.out, c is its original UTF-8 sequence, written as is for UTF-8 encodings
+func (e *Encoder) append_rune(r rune, c []byte) error {
+	switch e.encoding {
+	case TFE_Empty, TFE_UTF8, TFE_UTF8BOM:
+		e.out = append(e.out, c...)
+
+	case TFE_ASCII:
+		if r >= utf8.RuneSelf {
+			return e.unrepresentable(r)
+		}
+		e.out = append(e.out, byte(r))
+
+	case TFE_EightBit:
+		b, ok := e.charmap.EncodeRune(r)
+		if !ok {
+			return e.unrepresentable(r)
+		}
+		e.out = append(e.out, b)
+
+	case TFE_UTF16LE, TFE_UTF16LEBOM, TFE_UTF16BE, TFE_UTF16BEBOM:
+		var order binary.AppendByteOrder = binary.LittleEndian
+		if e.encoding == TFE_UTF16BE || e.encoding == TFE_UTF16BEBOM {
+			order = binary.BigEndian
+		}
+		if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+			e.out = order.AppendUint16(e.out, uint16(r1))
+			e.out = order.AppendUint16(e.out, uint16(r2))
+		} else {
+			e.out = order.AppendUint16(e.out, uint16(r))
+		}
+
+	case TFE_UTF32LE, TFE_UTF32LEBOM, TFE_UTF32BE, TFE_UTF32BEBOM:
+		var order binary.AppendByteOrder = binary.LittleEndian
+		if e.encoding == TFE_UTF32BE || e.encoding == TFE_UTF32BEBOM {
+			order = binary.BigEndian
+		}
+		e.out = order.AppendUint32(e.out, uint32(r))
+
+	default:
+		var src [utf8.UTFMax]byte
+		var buf [8]byte
+		e.cjk.Reset()
+		nDst, _, err := e.cjk.Transform(buf[:], src[:utf8.EncodeRune(src[:], r)], true)
+		if err != nil {
+			return e.unrepresentable(r)
+		}
+		e.out = append(e.out, buf[:nDst]...)
+	}
+	return nil
+}
+
+func (e *Encoder) unrepresentable(r rune) error {
+	return &UnrepresentableError{Char: r, Offset: e.offset, Encoding: e.encoding, CodePage: e.codePage}
+}
+
+// WriteTextFile writes text to file path in encoding, with BOM for BOM variants of Unicode encodings, converting EOL.
+// For TFE_EightBit, codePage is the name of the code page, "" for Windows 1252.
+// Text is completely encoded before the file is created, so file is not modified in case of an UnrepresentableError.
+func WriteTextFile(path string, text string, encoding TextFileEncoding, codePage string, eol EOLStyle) error {
+	var buf bytes.Buffer
+	e, err := NewEncoder(&buf, encoding, codePage, eol)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e, text); err != nil {
+		return err
+	}
+	if err := e.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0666)
+}
@@ -6,4 +6,7 @@ replace github.com/PieVio/TextAutoDecode => C:\Development\GitHub\Go\Packages\Te
 
 require github.com/PieVio/TextAutoDecode v0.0.0-00010101000000-000000000000
 
-require golang.org/x/text v0.26.0 // indirect
+require (
+	github.com/ulikunitz/xz v0.5.15 // indirect
+	golang.org/x/text v0.26.0 // indirect
+)
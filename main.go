@@ -0,0 +1,19 @@
+package main
+import ("fmt"; mg "github.com/PieVio/MyGlob")
+func main(){
+ for _, c := range []struct{p, s string; d mg.Dialect}{
+  {`a[\d].txt`, "a1.txt", mg.D_POSIX},
+  {`a[\d].txt`, "ad.txt", mg.D_POSIX},
+  {`a[\d].txt`, "a1.txt", mg.D_Windows},
+  {`a[\?].txt`, "a?.txt", mg.D_POSIX},
+  {`a[\[].txt`, "a[.txt", mg.D_POSIX},
+  {`C:\Temp\*.txt`, `c:\temp\x.txt`, mg.D_Windows},
+  {`\\srv\share\**\*.txt`, `\\SRV\share\a\b.txt`, mg.D_Windows},
+  {`dir/\*.txt`, `dir/*.txt`, mg.D_POSIX},
+  {`*.TXT`, `a.txt`, mg.D_POSIX},
+ } {
+  gs, err := mg.New(c.p).Dialect(c.d).Compile()
+  if err != nil { fmt.Println(c.p, "ERR", err); continue }
+  fmt.Printf("%-25q %-25q %v -> %v\n", c.p, c.s, c.d, gs.Match(c.s))
+ }
+}